@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CACHE_DIR_ENV = "CPM_CACHE_DIR"
+
+	cacheHostsFile    = "hosts.json"
+	cacheContractFile = "contract.json"
+	cacheStorageFile  = "storage.json"
+)
+
+// offline is set by the global '--offline' flag. When set all contract information is served from the cache
+var offline bool
+
+// cachedContract is the state of a contract at a specific block height of a network
+type cachedContract struct {
+	Magic  uint32
+	Height uint32
+	State  *state.Contract
+	// Storage is nil if it was not requested
+	Storage []result.KeyValue
+}
+
+// ContractCache stores contract states, manifests and storage dumps on disk so that they can be reused without
+// contacting the source network. Entries are stored under <dir>/<network magic>/<script hash>/<block height>/
+type ContractCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var contractCache = &ContractCache{}
+
+// getDir returns the cache directory, which is $CPM_CACHE_DIR or 'cpm' in the user cache directory (i.e. ~/.cache/cpm)
+func (cc *ContractCache) getDir() (string, error) {
+	if cc.dir != "" {
+		return cc.dir, nil
+	}
+	if dir := os.Getenv(CACHE_DIR_ENV); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return filepath.Join(dir, "cpm"), nil
+}
+
+// hostMagic returns the network magic of the host the last time it was contacted
+func (cc *ContractCache) hostMagic(host string) (uint32, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	hosts, err := cc.readHosts()
+	if err != nil {
+		return 0, false
+	}
+	magic, ok := hosts[host]
+	return magic, ok
+}
+
+func (cc *ContractCache) setHostMagic(host string, magic uint32) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	hosts, err := cc.readHosts()
+	if err != nil {
+		hosts = make(map[string]uint32)
+	}
+	if m, ok := hosts[host]; ok && m == magic {
+		return nil
+	}
+	hosts[host] = magic
+
+	dir, err := cc.getDir()
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(dir, cacheHostsFile), hosts)
+}
+
+func (cc *ContractCache) readHosts() (map[string]uint32, error) {
+	dir, err := cc.getDir()
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[string]uint32)
+	err = readJSONFile(filepath.Join(dir, cacheHostsFile), &hosts)
+	return hosts, err
+}
+
+func (cc *ContractCache) contractDir(magic uint32, scriptHash util.Uint160) (string, error) {
+	dir, err := cc.getDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.FormatUint(uint64(magic), 10), "0x"+scriptHash.StringLE()), nil
+}
+
+// load returns the cached contract at the given height, or the highest cached height if height is nil
+func (cc *ContractCache) load(magic uint32, scriptHash util.Uint160, height *uint32, withStorage bool) (*cachedContract, error) {
+	dir, err := cc.contractDir(magic, scriptHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var heights []uint32
+	if height != nil {
+		heights = []uint32{*height}
+	} else {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if h, err := strconv.ParseUint(e.Name(), 10, 32); err == nil {
+				heights = append(heights, uint32(h))
+			}
+		}
+		// try the newest state first
+		sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+	}
+
+	var lastErr = fmt.Errorf("contract %s is not cached", scriptHash.StringLE())
+	for _, h := range heights {
+		entryDir := filepath.Join(dir, strconv.FormatUint(uint64(h), 10))
+		contract := &cachedContract{Magic: magic, Height: h, State: new(state.Contract)}
+		if err := readJSONFile(filepath.Join(entryDir, cacheContractFile), contract.State); err != nil {
+			lastErr = err
+			continue
+		}
+		if withStorage {
+			if err := readJSONFile(filepath.Join(entryDir, cacheStorageFile), &contract.Storage); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		return contract, nil
+	}
+	return nil, lastErr
+}
+
+func (cc *ContractCache) store(scriptHash util.Uint160, contract *cachedContract) error {
+	dir, err := cc.contractDir(contract.Magic, scriptHash)
+	if err != nil {
+		return err
+	}
+	entryDir := filepath.Join(dir, strconv.FormatUint(uint64(contract.Height), 10))
+
+	if err := writeJSONFile(filepath.Join(entryDir, cacheContractFile), contract.State); err != nil {
+		return err
+	}
+	if contract.Storage != nil {
+		return writeJSONFile(filepath.Join(entryDir, cacheStorageFile), contract.Storage)
+	}
+	return nil
+}
+
+// loadContract returns the contract state, and its storage if withStorage is set, together with the network magic and
+// block height it belongs to. what describes the requested information for logging. A contract pinned to a height is served from the cache if possible, as is the latest
+// cached state in offline mode. Everything fetched from the network is added to the cache
+func loadContract(scriptHash util.Uint160, host string, height *uint32, withStorage bool, what string) (*cachedContract, error) {
+	if height != nil || offline {
+		if magic, ok := contractCache.hostMagic(host); ok {
+			if err := verifyNetworkMagic(host, magic); err != nil {
+				if offline {
+					return nil, err
+				}
+			} else if contract, err := contractCache.load(magic, scriptHash, height, withStorage); err == nil {
+				log.Debugf("Using cached state of contract '%s' at height %d", scriptHash.StringLE(), contract.Height)
+				return contract, nil
+			}
+		}
+	}
+
+	if offline {
+		return nil, fmt.Errorf("contract '%s' from %s is not available in the cache in offline mode", scriptHash.StringLE(), host)
+	}
+
+	client, err := clients.get(host)
+	if err != nil {
+		return nil, err
+	}
+
+	if height != nil {
+		log.Debugf("Attempting to fetch %s for contract '%s' at height %d using %s", what, scriptHash.StringLE(), *height, host)
+	} else {
+		log.Debugf("Attempting to fetch %s for contract '%s' using %s", what, scriptHash.StringLE(), host)
+	}
+
+	contract := &cachedContract{Magic: client.magic}
+	if height != nil {
+		contract.Height = *height
+		contract.State, contract.Storage, err = fetchHistoricContractState(client.Client, scriptHash, *height, withStorage)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		count, err := client.GetBlockCount()
+		if err != nil {
+			return nil, fmt.Errorf("getblockcount failed with: %w", err)
+		}
+		contract.Height = count - 1
+
+		contract.State, err = getContractState(client.Client, scriptHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		if withStorage {
+			contract.Storage, err = fetchStorage(client.Client, scriptHash)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := contractCache.setHostMagic(host, contract.Magic); err != nil {
+		log.Debugf("Failed to cache network magic of %s: %v", host, err)
+	}
+	if err := contractCache.store(scriptHash, contract); err != nil {
+		log.Debugf("Failed to cache contract '%s': %v", scriptHash.StringLE(), err)
+	}
+	return contract, nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSONFile writes the file through a temporary file so that readers never see partially written files
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
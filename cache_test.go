@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ContractCache(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := util.Uint160{}
+
+	t.Run("should return the newest cached height", func(t *testing.T) {
+		cache := &ContractCache{dir: t.TempDir()}
+		for _, height := range []uint32{9, 100, 20} {
+			contract := &cachedContract{Magic: 1, Height: height, State: &state.Contract{}, Storage: []result.KeyValue{}}
+			contract.State.UpdateCounter = uint16(height)
+			require.NoError(t, cache.store(c, contract))
+		}
+
+		contract, err := cache.load(1, c, nil, true)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(100), contract.Height)
+		assert.Equal(t, uint16(100), contract.State.UpdateCounter)
+
+		height := uint32(9)
+		contract, err = cache.load(1, c, &height, false)
+		require.NoError(t, err)
+		assert.Equal(t, uint16(9), contract.State.UpdateCounter)
+
+		_, err = cache.load(2, c, nil, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("should serve fetched contracts in offline mode", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{
+			{"getcontractstate", contractStateResult},
+			{"findstorage", `{"jsonrpc":"2.0","id":1,"result":{"results":[{"key":"AQ==","value":"Ag=="}],"truncated":false,"next":1}}`},
+		})
+		defer srv.Close()
+		t.Cleanup(func() { offline = false })
+
+		_, _, err := fetchContractState(c, srv.URL, nil)
+		require.NoError(t, err)
+		srv.Close()
+
+		offline = true
+		contractState, storage, err := fetchContractState(c, srv.URL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", contractState.Manifest.Name)
+		assert.Len(t, storage, 1)
+
+		_, err = fetchManifest(&util.Uint160{1}, srv.URL, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("contract '%s' from %s is not available in the cache", util.Uint160{1}.StringLE(), srv.URL))
+	})
+}
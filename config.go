@@ -4,9 +4,12 @@ import (
 	"cpm/generators"
 	_ "embed"
 	"fmt"
-	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/nspcc-dev/neo-go/pkg/util"
 	log "github.com/sirupsen/logrus"
@@ -23,30 +26,43 @@ var cfg = &CPMConfig{
 }
 
 type ContractConfig struct {
-	Label         string          `yaml:"label"`
-	ScriptHash    util.Uint160    `yaml:"script-hash"`
+	Label      string       `yaml:"label"`
+	ScriptHash util.Uint160 `yaml:"script-hash"`
+	// NNS and Native identify the contract by NNS domain or native contract name instead of by script hash. They are
+	// resolved to the script hash once, which is then recorded in the config
+	NNS           *string         `yaml:"nns,omitempty"`
+	Native        *string         `yaml:"native,omitempty"`
 	SourceNetwork *string         `yaml:"source-network,omitempty"`
+	SourceHeight  *uint32         `yaml:"source-height,omitempty"`
 	GenerateSdk   *bool           `yaml:"generate-sdk,omitempty"`
 	Download      *bool           `yaml:"download,omitempty"`
 	OnChain       *GenerateConfig `yaml:"on-chain,omitempty"`
 	OffChain      *GenerateConfig `yaml:"off-chain,omitempty"`
+	// SdkName overrides the manifest name as name of the generated classes, packages and modules
+	SdkName *string `yaml:"sdk-name,omitempty"`
+	// Bindings is the path of a neo-go binding config of the contract, which gives the SDKs structs and typed arrays
+	// and maps
+	Bindings *string `yaml:"bindings,omitempty"`
+	// AutoAdded is set for contracts that were added to the config as dependency of another contract
+	AutoAdded bool `yaml:"auto-added,omitempty"`
 }
 
 type GenerateConfig struct {
 	Languages       []string       `yaml:"languages"`
-	SdkDestinations SdkDestination `yaml:"destinations"`
+	SdkDestinations SdkDestination `yaml:"destinations,omitempty"`
+	// Templates are the directories with templates that override the built-in templates per language
+	Templates SdkDestination `yaml:"templates,omitempty"`
 }
 
+// SdkDestination holds a path per language, keyed by the language of the generator, i.e. 'python' or the language of a
+// generator plugin
 type SdkDestination struct {
-	Csharp *string `yaml:"csharp,omitempty"`
-	Golang *string `yaml:"go,omitempty"`
-	Java   *string `yaml:"java,omitempty"`
-	Python *string `yaml:"python,omitempty"`
-	TS     *string `yaml:"ts,omitempty"`
+	Paths map[string]string `yaml:",inline"`
 }
 
 type Defaults struct {
 	ContractSourceNetwork string          `yaml:"contract-source-network"`
+	ContractSourceHeight  *uint32         `yaml:"contract-source-height,omitempty"`
 	ContractDestination   string          `yaml:"contract-destination"`
 	ContractGenerateSdk   bool            `yaml:"contract-generate-sdk"`
 	ContractDownload      bool            `yaml:"contract-download,omitempty"`
@@ -55,6 +71,9 @@ type Defaults struct {
 }
 
 type CPMConfig struct {
+	// path of the loaded config file. Relative paths in the config are relative to its directory
+	path string
+
 	Defaults  Defaults         `yaml:"defaults"`
 	Contracts []ContractConfig `yaml:"contracts"`
 	Tools     struct {
@@ -63,142 +82,363 @@ type CPMConfig struct {
 			CanDownloadContract bool    `yaml:"canDownloadContract"`
 			ExecutablePath      *string `yaml:"executable-path,omitempty"`
 			ConfigPath          string  `yaml:"config-path"`
+			Downloader          string  `yaml:"downloader,omitempty"`
 		} `yaml:"neo-express"`
+		NeoGo struct {
+			CanGenerateSDK      bool `yaml:"canGenerateSDK"`
+			CanDownloadContract bool `yaml:"canDownloadContract"`
+			// ConfigPath is the config file of the neo-go node, its database is where contracts are downloaded to
+			ConfigPath string `yaml:"config-path"`
+		} `yaml:"neo-go,omitempty"`
+		// Generators are the executables of generator plugins by language. Executables named 'cpm-gen-<language>' in
+		// $PATH don't need to be configured
+		Generators map[string]string `yaml:"generators,omitempty"`
 	} `yaml:"tools"`
-	Networks []struct {
-		Label string   `yaml:"label"`
-		Hosts []string `yaml:"hosts"`
-	} `yaml:"networks"`
+	Networks []NetworkConfig `yaml:"networks"`
 }
 
-func LoadConfig() {
-	f, err := os.Open(DEFAULT_CONFIG_FILE)
+type NetworkConfig struct {
+	Label string   `yaml:"label"`
+	Hosts []string `yaml:"hosts"`
+	// Magic is the expected network magic. Hosts that report a different magic are skipped
+	Magic *uint32 `yaml:"magic,omitempty"`
+	// Timeout is the dial and request timeout of RPC calls to the hosts
+	Timeout *time.Duration `yaml:"timeout,omitempty"`
+	// Retries is the number of times a failing host is retried before the next host is tried
+	Retries *int `yaml:"retries,omitempty"`
+	// Backoff is the delay before the first retry, it doubles for every following retry
+	Backoff *time.Duration `yaml:"backoff,omitempty"`
+	// Headers are added to every request to the hosts, i.e. to pass an API key
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Proxy is the URL of the HTTP proxy to connect to the hosts through
+	Proxy string `yaml:"proxy,omitempty"`
+	// CAFile is a PEM file with additional CA certificates to verify the hosts with
+	CAFile string `yaml:"ca-file,omitempty"`
+	// ClientCert and ClientKey are PEM files with the certificate and key to authenticate to the hosts with
+	ClientCert         string `yaml:"client-cert,omitempty"`
+	ClientKey          string `yaml:"client-key,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify,omitempty"`
+	// NNS is the script hash of the NameService contract on this network. Defaults to the MainNet NameService
+	NNS *util.Uint160 `yaml:"nns,omitempty"`
+}
+
+// configFlag holds the value of the global '--config' flag
+var configFlag string
+
+// findConfigFile returns the path of the config file. It is the '--config' flag, the CPM_CONFIG environment variable or
+// the first cpm.yaml found in the current directory or its parent directories, in that order. If none is found the
+// path of cpm.yaml in the current directory is returned
+func findConfigFile() string {
+	if configFlag != "" {
+		return configFlag
+	}
+	if path := os.Getenv(CONFIG_ENV); path != "" {
+		return path
+	}
+
+	dir, err := os.Getwd()
 	if err != nil {
-		if os.IsNotExist(err) {
-			log.Fatalf("Config file %s not found. Run `cpm init` to create a default config", DEFAULT_CONFIG_FILE)
-		} else {
-			log.Fatal(err)
+		return DEFAULT_CONFIG_FILE
+	}
+	for {
+		path := filepath.Join(dir, DEFAULT_CONFIG_FILE)
+		if _, err := os.Stat(path); err == nil {
+			return path
 		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return DEFAULT_CONFIG_FILE
+		}
+		dir = parent
+	}
+}
+
+// resolvePath returns the path relative to the directory of the loaded config file if it is a relative path
+func (c *CPMConfig) resolvePath(path string) string {
+	if c.path == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.path), path)
+}
+
+// resolveExecutable resolves the path of an executable like resolvePath, but leaves plain names to be looked up in $PATH
+func (c *CPMConfig) resolveExecutable(path string) string {
+	if filepath.Base(path) == path {
+		return path
 	}
-	defer f.Close()
+	return c.resolvePath(path)
+}
+
+// LoadConfig reads the config file like readConfig and resolves the script hashes of contracts configured by NNS domain
+// or native contract name, which are written back to the config file
+func LoadConfig() {
+	readConfig()
+
+	resolved, err := cfg.resolveContractHashes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resolved {
+		cfg.saveToDisk()
+	}
+}
 
-	yamlData, _ := io.ReadAll(f)
-	if err := yaml.Unmarshal(yamlData, &cfg); err != nil {
+// readConfig reads the config file with all environment variables interpolated, registers its generator plugins and
+// applies the defaults to the contracts. It neither contacts any network nor changes the config file
+func readConfig() {
+	configPath := findConfigFile()
+	if configPath != DEFAULT_CONFIG_FILE {
+		log.Debugf("Using config file %s", configPath)
+	}
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		log.Fatalf("Config file %s not found. Run `cpm init` to create a default config", configPath)
+	}
+	root, err := parseConfigFile(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := root.Decode(cfg); err != nil {
 		log.Fatal(fmt.Errorf("failed to parse config file: %w", err))
 	}
+	cfg.path = configPath
 
-	// ensure all contract configs can be worked with directly
-	for i, c := range cfg.Contracts {
-		if c.SourceNetwork == nil {
-			cfg.Contracts[i].SourceNetwork = &cfg.Defaults.ContractSourceNetwork
+	if err := cfg.registerGenerators(); err != nil {
+		log.Fatal(err)
+	}
+	cfg.applyContractDefaults()
+}
+
+// registerGenerators registers the generator plugins configured in the tools section
+func (c *CPMConfig) registerGenerators() error {
+	for language, path := range c.Tools.Generators {
+		if err := generators.RegisterPlugin(language, c.resolveExecutable(path)); err != nil {
+			return err
 		}
-		if c.GenerateSdk == nil {
-			cfg.Contracts[i].GenerateSdk = &cfg.Defaults.ContractGenerateSdk
+	}
+	return nil
+}
+
+// registerConfigGenerators registers the generator plugins of the config file without loading the rest of the config.
+// Commands that work without a config, like 'generate', use it to know the plugins configured in cpm.yaml
+func registerConfigGenerators() error {
+	configPath := findConfigFile()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil
+	}
+	root, err := parseConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	var c CPMConfig
+	if err := root.Decode(&c); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	c.path = configPath
+	return c.registerGenerators()
+}
+
+// parseConfigFile reads the config file into a YAML tree with all environment variables interpolated
+func parseConfigFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	lookup, err := envLookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateNode(&root, lookup); err != nil {
+		return nil, fmt.Errorf("failed to interpolate config file: %w", err)
+	}
+	return &root, nil
+}
+
+// applyContractDefaults ensures all contract configs can be worked with directly
+func (c *CPMConfig) applyContractDefaults() {
+	for i, contract := range c.Contracts {
+		if contract.SourceNetwork == nil {
+			c.Contracts[i].SourceNetwork = &c.Defaults.ContractSourceNetwork
+		}
+		if contract.SourceHeight == nil {
+			c.Contracts[i].SourceHeight = c.Defaults.ContractSourceHeight
+		}
+		if contract.GenerateSdk == nil {
+			c.Contracts[i].GenerateSdk = &c.Defaults.ContractGenerateSdk
 		}
-		if c.Download == nil {
-			cfg.Contracts[i].Download = &cfg.Defaults.ContractDownload
+		if contract.Download == nil {
+			c.Contracts[i].Download = &c.Defaults.ContractDownload
 		}
 	}
 }
 
+// CreateDefaultConfig writes the default config to the path given by '--config' or CPM_CONFIG, or to cpm.yaml in the
+// current directory
 func CreateDefaultConfig() {
-	if _, err := os.Stat(DEFAULT_CONFIG_FILE); os.IsNotExist(err) {
-		err = os.WriteFile(DEFAULT_CONFIG_FILE, defaultConfig, 0644)
+	path := configFlag
+	if path == "" {
+		path = os.Getenv(CONFIG_ENV)
+	}
+	if path == "" {
+		path = DEFAULT_CONFIG_FILE
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		err = os.WriteFile(path, defaultConfig, 0644)
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Written %s\n", DEFAULT_CONFIG_FILE)
+		log.Infof("Written %s\n", path)
 	} else {
-		log.Fatalf("%s already exists", DEFAULT_CONFIG_FILE)
+		log.Fatalf("%s already exists", path)
 	}
 }
 
-func (c *CPMConfig) addContract(label string, scriptHash util.Uint160) {
-	for _, c := range cfg.Contracts {
-		if c.ScriptHash.Equals(scriptHash) {
+func (c *CPMConfig) addContract(label string, scriptHash util.Uint160, sourceHeight *uint32) {
+	for _, contract := range c.Contracts {
+		if contract.ScriptHash.Equals(scriptHash) {
 			return
 		}
 	}
-	cfg.Contracts = append(cfg.Contracts, ContractConfig{Label: label, ScriptHash: scriptHash})
+	c.Contracts = append(c.Contracts, ContractConfig{Label: label, ScriptHash: scriptHash, SourceHeight: sourceHeight})
 }
 
-func (c *CPMConfig) getHosts(networkLabel string) []string {
-	for _, network := range c.Networks {
+// addDependency adds a contract that was discovered as dependency of another contract. Returns false if the contract
+// is already in the config
+func (c *CPMConfig) addDependency(label string, scriptHash util.Uint160, sourceNetwork *string, sourceHeight *uint32) bool {
+	for _, contract := range c.Contracts {
+		if contract.ScriptHash.Equals(scriptHash) {
+			return false
+		}
+	}
+	c.Contracts = append(c.Contracts, ContractConfig{
+		Label:         label,
+		ScriptHash:    scriptHash,
+		SourceNetwork: sourceNetwork,
+		SourceHeight:  sourceHeight,
+		AutoAdded:     true,
+	})
+	return true
+}
+
+func (c *CPMConfig) getNetwork(networkLabel string) (*NetworkConfig, error) {
+	for i, network := range c.Networks {
 		if network.Label == networkLabel {
-			return network.Hosts
+			return &c.Networks[i], nil
 		}
 	}
-	log.Fatalf("Could not find hosts for label: %s", networkLabel)
-	return nil
+	return nil, fmt.Errorf("could not find network for label: %s", networkLabel)
 }
 
-func (c *CPMConfig) getSdkDestination(forLanguage string, sdkType string) string {
-	defaultLocation := generators.OutputRoot + sdkType + "/" + forLanguage + "/"
+func (c *CPMConfig) getHosts(networkLabel string) ([]string, error) {
+	for _, network := range c.Networks {
+		if network.Label == networkLabel {
+			return network.Hosts, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find hosts for label: %s", networkLabel)
+}
 
-	if c == nil {
-		return defaultLocation
+// get returns the destination configured for the language, or nil if there is none
+func (d SdkDestination) get(language string) *string {
+	if path, ok := d.Paths[language]; ok {
+		return &path
 	}
+	return nil
+}
 
-	var sdkTypePath SdkDestination
-	if sdkType == generators.SDKOnChain {
-		if c.Defaults.OnChain == nil {
-			return defaultLocation
+// languages returns the languages that have a path, the languages of registered generators first
+func (d SdkDestination) languages() []string {
+	var languages []string
+	for _, g := range generators.All() {
+		if d.get(g.Language()) != nil {
+			languages = append(languages, g.Language())
 		}
-		sdkTypePath = c.Defaults.OnChain.SdkDestinations
-	} else {
-		if c.Defaults.OffChain == nil {
-			return defaultLocation
+	}
+	for _, l := range slices.Sorted(maps.Keys(d.Paths)) {
+		if generators.Get(l) == nil {
+			languages = append(languages, l)
 		}
-		sdkTypePath = c.Defaults.OffChain.SdkDestinations
 	}
+	return languages
+}
 
-	switch forLanguage {
-	case LANG_PYTHON:
-		if path := sdkTypePath.Python; path != nil {
-			return EnsureSuffix(*path)
-		}
-		return defaultLocation
-	case LANG_GO:
-		if path := sdkTypePath.Golang; path != nil {
-			return EnsureSuffix(*path)
-		}
-		return defaultLocation
-	case LANG_JAVA:
-		if path := sdkTypePath.Java; path != nil {
-			return EnsureSuffix(*path)
-		}
-		return defaultLocation
-	case LANG_CSHARP:
-		if path := sdkTypePath.Csharp; path != nil {
-			return EnsureSuffix(*path)
-		}
-		return defaultLocation
-	case LANG_TYPESCRIPT:
-		if path := sdkTypePath.TS; path != nil {
-			return EnsureSuffix(*path)
-		}
-		return defaultLocation
-	default:
-		return defaultLocation
+// generateConfig returns the on-chain or off-chain config, which may be nil
+func generateConfig(onChain, offChain *GenerateConfig, sdkType string) *GenerateConfig {
+	if sdkType == generators.SDKOnChain {
+		return onChain
 	}
+	return offChain
 }
 
-func (c *CPMConfig) saveToDisk() {
-	f, err := os.Create(DEFAULT_CONFIG_FILE)
-	if err != nil {
-		log.Fatal(err)
+// getSdkDestination returns the directory the SDK of the language and type is written to. It is resolved in layers: the
+// destination given on the command line, then the destination of the contract, then the default destination and finally
+// the built-in 'cpm_out/<type>/<language>'. 'flag' and 'contract' may be empty
+func (c *CPMConfig) getSdkDestination(flag string, contract *ContractConfig, forLanguage string, sdkType string) string {
+	destination, source := c.sdkDestination(flag, contract, forLanguage, sdkType)
+	if contract != nil {
+		log.Debugf("SDK destination of %s %s SDK for contract '%s' is %s (%s)", sdkType, forLanguage, contract.Label, destination, source)
+	} else {
+		log.Debugf("SDK destination of %s %s SDK is %s (%s)", sdkType, forLanguage, destination, source)
 	}
-	defer f.Close()
+	return destination
+}
 
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return
+// sdkDestination resolves the SDK destination like getSdkDestination and also returns where it was found
+func (c *CPMConfig) sdkDestination(flag string, contract *ContractConfig, forLanguage string, sdkType string) (string, string) {
+	if flag != "" {
+		return EnsureSuffix(flag), "command line"
+	}
+	if c == nil {
+		return EnsureSuffix(generators.OutputRoot + sdkType + "/" + forLanguage), "built-in default"
+	}
+	if path, source := c.languagePath(contract, forLanguage, sdkType, func(gc *GenerateConfig) SdkDestination {
+		return gc.SdkDestinations
+	}); path != nil {
+		return EnsureSuffix(c.resolvePath(*path)), source
 	}
+	return EnsureSuffix(c.resolvePath(generators.OutputRoot + sdkType + "/" + forLanguage)), "built-in default"
+}
 
-	_, err = f.Write(data)
-	if err != nil {
-		log.Fatal(err)
+// getTemplateDir returns the directory with the templates that override the built-in templates of the language and
+// type, or an empty string to use the built-in templates. It is resolved in layers like getSdkDestination
+func (c *CPMConfig) getTemplateDir(flag string, contract *ContractConfig, forLanguage string, sdkType string) string {
+	if flag != "" {
+		return flag
+	}
+	if c == nil {
+		return ""
+	}
+	path, source := c.languagePath(contract, forLanguage, sdkType, func(gc *GenerateConfig) SdkDestination {
+		return gc.Templates
+	})
+	if path == nil {
+		return ""
+	}
+	log.Debugf("Templates of %s %s SDK are in %s (%s)", sdkType, forLanguage, c.resolvePath(*path), source)
+	return c.resolvePath(*path)
+}
+
+// languagePath returns the path of the language that paths selects from the contract config, or else from the
+// defaults, and where it was found. The path is nil if neither configures one
+func (c *CPMConfig) languagePath(contract *ContractConfig, forLanguage string, sdkType string, paths func(*GenerateConfig) SdkDestination) (*string, string) {
+	if contract != nil {
+		if gc := generateConfig(contract.OnChain, contract.OffChain, sdkType); gc != nil {
+			if path := paths(gc).get(forLanguage); path != nil {
+				return path, "contract"
+			}
+		}
+	}
+	if gc := generateConfig(c.Defaults.OnChain, c.Defaults.OffChain, sdkType); gc != nil {
+		if path := paths(gc).get(forLanguage); path != nil {
+			return path, "defaults"
+		}
 	}
+	return nil, ""
 }
 
 type EnumValue struct {
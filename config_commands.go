@@ -0,0 +1,166 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"cpm/generators"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "xxxxx"
+
+// configSchema is the JSON Schema of cpm.yaml, which editors use for completion and validation
+//
+//go:embed docs/cpm.schema.json
+var configSchema []byte
+
+// secretKeys are config keys whose values are never shown. All values of a mapping with such a key are hidden
+var secretKeys = map[string]bool{
+	"headers": true,
+}
+
+func handleCliConfigShow(*cli.Context) error {
+	readConfig()
+
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	redactNode(&node, false)
+	omitUnresolvedHashes(&node, cfg.Contracts)
+
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// omitUnresolvedHashes removes the script hash from the encoded contracts that are configured by NNS domain or native
+// contract name and were not resolved yet. config show does not resolve them, so the hash would be all zeros
+func omitUnresolvedHashes(node *yaml.Node, contracts []ContractConfig) {
+	var contractsNode *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "contracts" {
+			contractsNode = node.Content[i+1]
+		}
+	}
+	if contractsNode == nil {
+		return
+	}
+	for i, c := range contracts {
+		if i >= len(contractsNode.Content) || !c.ScriptHash.Equals(util.Uint160{}) {
+			continue
+		}
+		contract := contractsNode.Content[i]
+		for j := 0; j+1 < len(contract.Content); j += 2 {
+			if contract.Content[j].Value == "script-hash" {
+				contract.Content = append(contract.Content[:j], contract.Content[j+2:]...)
+				break
+			}
+		}
+	}
+}
+
+func handleCliConfigValidate(*cli.Context) error {
+	path := findConfigFile()
+	problems, err := validateConfig(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p.format(path))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), path)
+	}
+	log.Infof("%s is valid", path)
+	return nil
+}
+
+func handleCliConfigSchema(*cli.Context) error {
+	schema, err := generatorSchema()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(schema)
+	return err
+}
+
+// generatorSchema returns the JSON Schema of cpm.yaml with the languages of the registered generators, so languages of
+// generator plugins found in $PATH are completed like the built-in ones
+func generatorSchema() ([]byte, error) {
+	var schema map[string]any
+	if err := json.Unmarshal(configSchema, &schema); err != nil {
+		return nil, fmt.Errorf("invalid config schema: %w", err)
+	}
+	definitions := schema["definitions"].(map[string]any)
+
+	paths := make(map[string]any)
+	for _, g := range generators.All() {
+		paths[g.Language()] = map[string]any{"type": "string"}
+	}
+	for _, name := range []string{"destinations", "templates"} {
+		definitions[name].(map[string]any)["properties"] = paths
+	}
+	for name, sdkType := range map[string]string{"onChain": generators.SDKOnChain, "offChain": generators.SDKOffChain} {
+		properties := definitions[name].(map[string]any)["properties"].(map[string]any)
+		items := properties["languages"].(map[string]any)["items"].(map[string]any)
+		items["anyOf"].([]any)[0] = map[string]any{"enum": generators.Languages(sdkType)}
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// redactNode hides the values of secret keys and the passwords and query parameters of URLs
+func redactNode(node *yaml.Node, secret bool) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if secret && node.Value != "" {
+			node.Value = redacted
+		} else {
+			node.Value = redactURL(node.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			redactNode(node.Content[i+1], secret || secretKeys[node.Content[i].Value])
+		}
+	default:
+		for _, child := range node.Content {
+			redactNode(child, secret)
+		}
+	}
+}
+
+// redactURL hides the password and query parameter values of a URL, other values are returned as they are
+func redactURL(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for k := range query {
+			query.Set(k, redacted)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// configEditor changes the text of a config file in place. Changes are located through the YAML tree of the file, so
+// that comments, formatting and key order of everything that is not changed stay exactly as they are
+type configEditor struct {
+	data []byte
+	root *yaml.Node
+	// lineStarts holds the byte offset of the start of every line
+	lineStarts []int
+	edits      []textEdit
+}
+
+// textEdit replaces data[start:end] by text
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+func newConfigEditor(data []byte) (*configEditor, error) {
+	e := &configEditor{data: data, root: new(yaml.Node), lineStarts: []int{0}}
+	if err := yaml.Unmarshal(data, e.root); err != nil {
+		return nil, err
+	}
+	for i, b := range data {
+		if b == '\n' {
+			e.lineStarts = append(e.lineStarts, i+1)
+		}
+	}
+	return e, nil
+}
+
+// offset returns the byte offset of a 1-based line and column as reported by the YAML parser
+func (e *configEditor) offset(line, column int) int {
+	if line > len(e.lineStarts) {
+		return len(e.data)
+	}
+	offset := e.lineStarts[line-1]
+	for i := 1; i < column && offset < len(e.data) && e.data[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRune(e.data[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// lineEnd returns the byte offset of the end of the line, including its newline
+func (e *configEditor) lineEnd(line int) int {
+	if line >= len(e.lineStarts) {
+		return len(e.data)
+	}
+	return e.lineStarts[line]
+}
+
+// lastLine returns the last line the node spans
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastLine(child))
+	}
+	return line
+}
+
+// mappingValue returns the key and value node of key in the mapping, or nil if the key is not present
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func (e *configEditor) document() *yaml.Node {
+	if e.root.Kind == yaml.DocumentNode && len(e.root.Content) > 0 {
+		return e.root.Content[0]
+	}
+	return nil
+}
+
+// contracts returns the items of the contracts section in the file
+func (e *configEditor) contracts() []*yaml.Node {
+	_, contracts := mappingValue(e.document(), "contracts")
+	if contracts == nil || contracts.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return contracts.Content
+}
+
+// insertLines inserts the text as new lines after the line
+func (e *configEditor) insertLines(afterLine int, text string) {
+	offset := e.lineEnd(afterLine)
+	if offset > 0 && e.data[offset-1] != '\n' {
+		text = "\n" + text
+	}
+	e.edits = append(e.edits, textEdit{start: offset, end: offset, text: text})
+}
+
+// appendContracts adds the contracts to the end of the contracts section, indented like the existing entries
+func (e *configEditor) appendContracts(contracts []ContractConfig) error {
+	var entries []string
+	for _, c := range contracts {
+		entry, err := marshalIndented(c)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	doc := e.document()
+	key, value := mappingValue(doc, "contracts")
+	switch {
+	case value != nil && value.Kind == yaml.SequenceNode && value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0:
+		// the dash of a block sequence item is 2 columns before the item
+		indent := strings.Repeat(" ", value.Content[0].Column-3)
+		e.insertLines(lastLine(value), sequenceItems(entries, indent))
+	case key != nil:
+		// an empty or flow style section, i.e. 'contracts: []', is replaced by a block sequence
+		if value.Kind == yaml.SequenceNode {
+			for i := len(value.Content) - 1; i >= 0; i-- {
+				entry, err := marshalIndented(value.Content[i])
+				if err != nil {
+					return err
+				}
+				entries = append([]string{entry}, entries...)
+			}
+		}
+		indent := strings.Repeat(" ", key.Column-1+2)
+		text := "contracts:\n" + sequenceItems(entries, indent)
+		// an empty value has the position of the next token, so scalars are expected on the line of the key
+		endLine := key.Line
+		if value.Kind != yaml.ScalarNode {
+			endLine = lastLine(value)
+		}
+		end := e.lineEnd(endLine)
+		if end == len(e.data) && (end == 0 || e.data[end-1] != '\n') {
+			text = strings.TrimSuffix(text, "\n")
+		}
+		e.edits = append(e.edits, textEdit{start: e.offset(key.Line, key.Column), end: end, text: text})
+	case doc != nil && doc.Kind == yaml.MappingNode && doc.Style&yaml.FlowStyle == 0:
+		e.insertLines(len(e.lineStarts), "contracts:\n"+sequenceItems(entries, "  "))
+	default:
+		return fmt.Errorf("the config is not a YAML mapping")
+	}
+	return nil
+}
+
+// setValue sets the key of the mapping to the value. An existing single line value is replaced, otherwise the key is
+// added as the last key of the mapping
+func (e *configEditor) setValue(mapping *yaml.Node, key string, value any) error {
+	if mapping.Style&yaml.FlowStyle != 0 {
+		return fmt.Errorf("flow style mapping at line %d can't be edited", mapping.Line)
+	}
+	rendered, err := marshalIndented(map[string]any{key: value})
+	if err != nil {
+		return err
+	}
+
+	if _, old := mappingValue(mapping, key); old != nil {
+		if old.Kind != yaml.ScalarNode || lastLine(old) != old.Line || old.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return fmt.Errorf("value of '%s' at line %d can't be edited", key, old.Line)
+		}
+		start := e.offset(old.Line, old.Column)
+		end := scalarEnd(e.data, start, old)
+		text := strings.TrimSuffix(strings.TrimPrefix(rendered, key+":"), "\n")
+		e.edits = append(e.edits, textEdit{start: start, end: end, text: strings.TrimLeft(text, " ")})
+		return nil
+	}
+
+	if len(mapping.Content) == 0 {
+		return fmt.Errorf("empty mapping at line %d can't be edited", mapping.Line)
+	}
+	indent := strings.Repeat(" ", mapping.Content[0].Column-1)
+	e.insertLines(lastLine(mapping), indent+rendered)
+	return nil
+}
+
+// scalarEnd returns the byte offset of the end of the single line scalar starting at start
+func scalarEnd(data []byte, start int, node *yaml.Node) int {
+	switch node.Style {
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(data); i++ {
+			if data[i] == '\'' {
+				if i+1 < len(data) && data[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+	}
+	if node.Value == "" && node.Tag == "!!null" {
+		return start
+	}
+	return start + len(node.Value)
+}
+
+// apply returns the text with all edits applied
+func (e *configEditor) apply() []byte {
+	edits := append([]textEdit(nil), e.edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	// edits are applied from the end, so that offsets stay valid and insertions at the same offset keep their order
+	data := append([]byte(nil), e.data...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		data = append(data[:edit.start], append([]byte(edit.text), data[edit.end:]...)...)
+	}
+	return data
+}
+
+// marshalIndented encodes the value as block style YAML with an indentation of 2 spaces. Quoted strings use single
+// quotes like cpm.yaml.default does
+func marshalIndented(v any) (string, error) {
+	node, ok := v.(*yaml.Node)
+	if !ok {
+		node = new(yaml.Node)
+		if err := node.Encode(v); err != nil {
+			return "", err
+		}
+	}
+	singleQuote(node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func singleQuote(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Style == yaml.DoubleQuotedStyle &&
+		!strings.ContainsFunc(node.Value, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		node.Style = yaml.SingleQuotedStyle
+	}
+	for _, child := range node.Content {
+		singleQuote(child)
+	}
+}
+
+// sequenceItems formats the entries as items of a block sequence with the dashes at the indentation
+func sequenceItems(entries []string, indent string) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		for i, line := range strings.Split(strings.TrimSuffix(entry, "\n"), "\n") {
+			switch {
+			case i == 0:
+				sb.WriteString(indent + "- " + line)
+			case line == "":
+			default:
+				sb.WriteString(indent + "  " + line)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// saveToDisk records changes to the contracts in the config file: contracts that are not in the file yet are appended
+// to the contracts section and resolved script hashes are added to their contract. Everything else in the file is left
+// as it is, including references to environment variables
+func (c *CPMConfig) saveToDisk() {
+	path := c.path
+	if path == "" {
+		path = DEFAULT_CONFIG_FILE
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		c.writeToDisk(path)
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	e, err := newConfigEditor(data)
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to parse config file: %w", err))
+	}
+
+	existing := e.contracts()
+	for i, contract := range c.Contracts {
+		if i >= len(existing) {
+			break
+		}
+		if contract.ScriptHash.Equals(util.Uint160{}) {
+			continue
+		}
+		if _, hash := mappingValue(existing[i], "script-hash"); hash != nil {
+			var current util.Uint160
+			if hash.Decode(&current) == nil && current.Equals(contract.ScriptHash) {
+				continue
+			}
+		}
+		if err := e.setValue(existing[i], "script-hash", contract.ScriptHash); err != nil {
+			log.Warnf("Failed to record script hash %s of contract '%s' in %s: %v", contract.ScriptHash.StringLE(),
+				contract.Label, path, err)
+		}
+	}
+	if len(c.Contracts) > len(existing) {
+		if err := e.appendContracts(c.Contracts[len(existing):]); err != nil {
+			log.Fatal(fmt.Errorf("failed to add contracts to %s: %w", path, err))
+		}
+	}
+
+	if len(e.edits) == 0 {
+		return
+	}
+	if err := os.WriteFile(path, e.apply(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeToDisk writes the complete config to a new file
+func (c *CPMConfig) writeToDisk(path string) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// removeItem removes the item of the block sequence with all its lines
+func (e *configEditor) removeItem(sequence *yaml.Node, i int) error {
+	if sequence.Style&yaml.FlowStyle != 0 {
+		return fmt.Errorf("flow style sequence at line %d can't be edited", sequence.Line)
+	}
+	item := sequence.Content[i]
+	e.edits = append(e.edits, textEdit{start: e.lineStarts[item.Line-1], end: e.lineEnd(lastLine(item))})
+	return nil
+}
+
+// removeContract removes the contract at the index from the config and from the config file
+func (c *CPMConfig) removeContract(i int) error {
+	path := c.path
+	if path == "" {
+		path = DEFAULT_CONFIG_FILE
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	e, err := newConfigEditor(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	_, contracts := mappingValue(e.document(), "contracts")
+	if contracts == nil || contracts.Kind != yaml.SequenceNode || i >= len(contracts.Content) {
+		return fmt.Errorf("contract '%s' is not in %s", c.Contracts[i].Label, path)
+	}
+	if err := e.removeItem(contracts, i); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, e.apply(), 0644); err != nil {
+		return err
+	}
+	c.Contracts = append(c.Contracts[:i], c.Contracts[i+1:]...)
+	return nil
+}
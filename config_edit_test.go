@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestConfig(t *testing.T, path string) *CPMConfig {
+	root, err := parseConfigFile(path)
+	require.NoError(t, err)
+	c := &CPMConfig{path: path}
+	require.NoError(t, root.Decode(c))
+	c.applyContractDefaults()
+	return c
+}
+
+func Test_SaveConfig(t *testing.T) {
+	scriptHash := util.Uint160{1, 2, 3}
+	hashLine := "script-hash: '0x0000000000000000000000000000000000030201'\n"
+
+	t.Run("adding a contract should keep the rest of the file", func(t *testing.T) {
+		path := writeTestConfig(t, string(defaultConfig))
+		c := loadTestConfig(t, path)
+		c.addContract("unknown", scriptHash, nil)
+		c.saveToDisk()
+
+		last := "    script-hash: '0xf05651bc505fd5c7d36593f6e8409932342f9085'\n"
+		expected := strings.Replace(string(defaultConfig), last, last+"  - label: unknown\n    "+hashLine, 1)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	})
+
+	t.Run("resolved script hashes should be added to their contract", func(t *testing.T) {
+		t.Setenv("API_KEY", "secret")
+		config := `
+contracts:
+- label: Flamingo
+  nns: flamingo.neo # resolved once
+- label: GAS
+  native: GasToken
+
+networks:
+- label: mainnet
+  hosts: ['http://127.0.0.1:10332']
+  headers:
+    X-Api-Key: ${API_KEY}
+`
+		path := writeTestConfig(t, config)
+		c := loadTestConfig(t, path)
+		c.Contracts[0].ScriptHash = scriptHash
+		c.saveToDisk()
+
+		expected := strings.Replace(strings.TrimPrefix(config, "\n"), "# resolved once\n", "# resolved once\n  "+hashLine, 1)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	})
+
+	t.Run("an empty contracts section should be replaced", func(t *testing.T) {
+		path := writeTestConfig(t, "# contracts\ncontracts: []\nnetworks: []\n")
+		c := loadTestConfig(t, path)
+		sourceNetwork := "mainnet"
+		c.addDependency("dep", scriptHash, &sourceNetwork, nil)
+		c.saveToDisk()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "# contracts\ncontracts:\n  - label: dep\n    "+hashLine+"    source-network: mainnet\n"+
+			"    auto-added: true\nnetworks: []\n", string(data))
+	})
+
+	t.Run("saving an unchanged config should keep the file as it is", func(t *testing.T) {
+		path := writeTestConfig(t, string(defaultConfig))
+		loadTestConfig(t, path).saveToDisk()
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, string(defaultConfig), string(data))
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cpm/generators"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FindConfigFile(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "packages", "app")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, DEFAULT_CONFIG_FILE), nil, 0644))
+	t.Chdir(sub)
+
+	t.Run("should find config in parent directory", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(root, DEFAULT_CONFIG_FILE), findConfigFile())
+	})
+
+	t.Run("should prefer environment variable", func(t *testing.T) {
+		t.Setenv(CONFIG_ENV, "env.yaml")
+		assert.Equal(t, "env.yaml", findConfigFile())
+	})
+
+	t.Run("should prefer flag", func(t *testing.T) {
+		t.Setenv(CONFIG_ENV, "env.yaml")
+		configFlag = "flag.yaml"
+		t.Cleanup(func() { configFlag = "" })
+		assert.Equal(t, "flag.yaml", findConfigFile())
+	})
+}
+
+func Test_ResolvePath(t *testing.T) {
+	c := &CPMConfig{path: filepath.Join("monorepo", DEFAULT_CONFIG_FILE)}
+
+	assert.Equal(t, filepath.Join("monorepo", "default.neo-express"), c.resolvePath("default.neo-express"))
+	assert.Equal(t, "/abs/default.neo-express", c.resolvePath("/abs/default.neo-express"))
+	assert.Equal(t, filepath.Join("monorepo", "cpm_out", "onchain", "go")+"/", c.getSdkDestination("", nil, LANG_GO, generators.SDKOnChain))
+
+	// without a loaded config paths are relative to the current directory
+	assert.Equal(t, "default.neo-express", (&CPMConfig{}).resolvePath("default.neo-express"))
+}
+
+func Test_GetNetwork(t *testing.T) {
+	c := &CPMConfig{Networks: []NetworkConfig{{Label: "mainnet", Hosts: []string{"http://seed1.neo.org:10332"}}}}
+
+	t.Run("should find network by label", func(t *testing.T) {
+		network, err := c.getNetwork("mainnet")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://seed1.neo.org:10332"}, network.Hosts)
+	})
+
+	t.Run("should return an error for unknown labels", func(t *testing.T) {
+		_, err := c.getNetwork("testnet")
+		assert.ErrorContains(t, err, "could not find network for label: testnet")
+	})
+}
+
+func Test_SdkDestination(t *testing.T) {
+	defaultDest, contractDest := "sdk/defaults", "sdk/contract"
+	c := &CPMConfig{
+		path: filepath.Join("monorepo", DEFAULT_CONFIG_FILE),
+		Defaults: Defaults{
+			OnChain: &GenerateConfig{SdkDestinations: SdkDestination{Paths: map[string]string{LANG_PYTHON: defaultDest, LANG_GO: defaultDest}}},
+		},
+	}
+	contract := &ContractConfig{
+		Label:   "A",
+		OnChain: &GenerateConfig{SdkDestinations: SdkDestination{Paths: map[string]string{LANG_PYTHON: contractDest}}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		flag     string
+		contract *ContractConfig
+		language string
+		sdkType  string
+		want     string
+		source   string
+	}{
+		{"flag", "out", contract, LANG_PYTHON, generators.SDKOnChain, "out/", "command line"},
+		{"contract", "", contract, LANG_PYTHON, generators.SDKOnChain, filepath.Join("monorepo", "sdk", "contract") + "/", "contract"},
+		{"defaults", "", contract, LANG_GO, generators.SDKOnChain, filepath.Join("monorepo", "sdk", "defaults") + "/", "defaults"},
+		{"defaults without contract", "", nil, LANG_PYTHON, generators.SDKOnChain, filepath.Join("monorepo", "sdk", "defaults") + "/", "defaults"},
+		{"built-in", "", contract, LANG_PYTHON, generators.SDKOffChain, filepath.Join("monorepo", "cpm_out", "offchain", "python") + "/", "built-in default"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dest, source := c.sdkDestination(tc.flag, tc.contract, tc.language, tc.sdkType)
+			assert.Equal(t, tc.want, dest)
+			assert.Equal(t, tc.source, source)
+		})
+	}
+}
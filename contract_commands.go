@@ -0,0 +1,207 @@
+package main
+
+import (
+	"cpm/generators"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func handleCliAdd(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return fmt.Errorf("expected a script hash, native contract name or NNS domain")
+	}
+	LoadConfig()
+
+	networkLabel := cfg.Defaults.ContractSourceNetwork
+	if cCtx.IsSet("network") {
+		networkLabel = cCtx.String("network")
+	}
+	network, err := cfg.getNetwork(networkLabel)
+	if err != nil {
+		return err
+	}
+
+	scriptHash, err := resolveScriptHash(cCtx.Args().First(), network.Hosts, network.nnsHash())
+	if err != nil {
+		return err
+	}
+	for _, c := range cfg.Contracts {
+		if c.ScriptHash.Equals(scriptHash) {
+			return fmt.Errorf("contract %s is already in %s as '%s'", scriptHash.StringLE(), cfg.path, c.Label)
+		}
+	}
+
+	contract := ContractConfig{ScriptHash: scriptHash}
+	if cCtx.IsSet("network") {
+		contract.SourceNetwork = &networkLabel
+	}
+	if cCtx.Bool("no-download") {
+		contract.Download = new(bool)
+	}
+	for _, sdkType := range []string{generators.SDKOnChain, generators.SDKOffChain} {
+		languages, err := languagesFlag(cCtx, sdkType)
+		if err != nil {
+			return err
+		}
+		if languages == nil {
+			continue
+		}
+		gc := &GenerateConfig{Languages: languages}
+		if sdkType == generators.SDKOnChain {
+			contract.OnChain = gc
+		} else {
+			contract.OffChain = gc
+		}
+		generateSdk := true
+		contract.GenerateSdk = &generateSdk
+	}
+
+	// the manifest name is the label, it is fetched at the height the contract will be downloaded at
+	var m *manifest.Manifest
+	err = tryHosts(network.Hosts, log.WarnLevel, func(host string) error {
+		m, err = fetchManifest(&scriptHash, host, cfg.Defaults.ContractSourceHeight)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest of contract %s. Use '--log-level DEBUG' for more information",
+			scriptHash.StringLE())
+	}
+	contract.Label = m.Name
+
+	cfg.Contracts = append(cfg.Contracts, contract)
+	cfg.saveToDisk()
+	cfg.applyContractDefaults()
+	log.Infof("Added contract '%s' (%s) to %s", contract.Label, scriptHash.StringLE(), cfg.path)
+
+	if !cCtx.Bool("run") {
+		return nil
+	}
+
+	lock, err := loadLockFile(lockFilePath())
+	if err != nil {
+		return err
+	}
+	downloader := NewDownloader(cfg.Defaults.ContractDestination, cfg.resolvePath(cfg.Tools.NeoExpress.ConfigPath))
+	if err := processContract(&cfg.Contracts[len(cfg.Contracts)-1], downloader, lock, false); err != nil {
+		return err
+	}
+	if err := lock.saveToDisk(lockFilePath()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", lockFilePath(), err)
+	}
+	return nil
+}
+
+// languagesFlag returns the languages of the '--onchain' or '--offchain' flag, which are named after the SDK type, or
+// nil if the flag is not set
+func languagesFlag(cCtx *cli.Context, sdkType string) ([]string, error) {
+	if !cCtx.IsSet(sdkType) {
+		return nil, nil
+	}
+	var languages []string
+	for _, value := range cCtx.StringSlice(sdkType) {
+		for _, l := range strings.Split(value, ",") {
+			l = strings.TrimSpace(l)
+			if !slices.Contains(generators.Languages(sdkType), l) {
+				return nil, fmt.Errorf("language '%s' is not supported for %s SDKs. Valid values are %s", l,
+					sdkTypeKeys[sdkType], strings.Join(generators.Languages(sdkType), ", "))
+			}
+			if !slices.Contains(languages, l) {
+				languages = append(languages, l)
+			}
+		}
+	}
+	return languages, nil
+}
+
+func handleCliRemove(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return fmt.Errorf("expected the label or script hash of a contract")
+	}
+	LoadConfig()
+
+	i, err := findContractIndex(cCtx.Args().First())
+	if err != nil {
+		return err
+	}
+	contract := cfg.Contracts[i]
+
+	if *contract.GenerateSdk && !cCtx.Bool("keep-sdk") {
+		removeGeneratedSDKs(&contract)
+	}
+
+	if err := cfg.removeContract(i); err != nil {
+		return err
+	}
+	log.Infof("Removed contract '%s' (%s) from %s", contract.Label, contract.ScriptHash.StringLE(), cfg.path)
+
+	lock, err := loadLockFile(lockFilePath())
+	if err != nil {
+		return err
+	}
+	if lock.get(contract.ScriptHash) != nil {
+		lock.retain(cfg.Contracts)
+		if err := lock.saveToDisk(lockFilePath()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", lockFilePath(), err)
+		}
+	}
+	return nil
+}
+
+// removeGeneratedSDKs removes the SDKs generated for the contract in all languages it is configured for. Unless the
+// contract has an 'sdk-name', the SDK names are derived from the contract manifest, so SDKs can only be removed if the
+// manifest can be fetched
+func removeGeneratedSDKs(c *ContractConfig) {
+	m := &manifest.Manifest{}
+	if c.SdkName == nil {
+		hosts, err := cfg.getHosts(*c.SourceNetwork)
+		if err == nil {
+			err = tryHosts(hosts, log.DebugLevel, func(host string) error {
+				var err error
+				m, err = fetchManifest(&c.ScriptHash, host, c.SourceHeight)
+				return err
+			})
+		}
+		if err != nil {
+			log.Warnf("Failed to fetch manifest of contract '%s' to find its SDKs, they are not removed: %v", c.Label, err)
+			return
+		}
+	}
+
+	name := c.sdkName(m)
+	if !filepath.IsLocal(name) {
+		log.Warnf("SDK name '%s' of contract '%s' can't be used as SDK path, its SDKs are not removed", name, c.Label)
+		return
+	}
+
+	for _, sdkType := range []string{generators.SDKOnChain, generators.SDKOffChain} {
+		for _, l := range c.languages(sdkType) {
+			destination := cfg.getSdkDestination("", c, l, sdkType)
+			path := sdkOutputPath(l, sdkType, destination, name)
+			if !isBelow(destination, path) {
+				log.Warnf("SDK %s is not inside its destination %s, it is not removed", path, destination)
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			if err := os.RemoveAll(path); err != nil {
+				log.Warnf("Failed to remove SDK %s: %v", path, err)
+				continue
+			}
+			log.Infof("Removed SDK %s", path)
+		}
+	}
+}
+
+// isBelow returns true if path is inside dir and not dir itself
+func isBelow(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AddRemoveContract(t *testing.T) {
+	srv := NewTestRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+	defer srv.Close()
+
+	contracts := `defaults:
+  contract-source-network: mainnet
+  off-chain:
+    languages:
+      - python
+# contracts are added here
+contracts:
+  - label: existing
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+`
+	networks := fmt.Sprintf(`networks:
+  - label: mainnet
+    hosts:
+      - http://%s
+`, srv.Listener.Addr())
+	config := contracts + networks
+	path := writeTestConfig(t, config)
+	dir := filepath.Dir(path)
+
+	saved := cfg
+	t.Cleanup(func() {
+		cfg = saved
+		configFlag = ""
+	})
+	run := func(args ...string) error {
+		cfg = &CPMConfig{}
+		return newApp().Run(append([]string{"cpm", "-C", path}, args...))
+	}
+
+	scriptHash := util.Uint160{1, 2, 3}
+	t.Run("should add the contract labeled by its manifest name", func(t *testing.T) {
+		require.NoError(t, run("add", "--onchain", "go,python", "--no-download", scriptHash.StringLE()))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, contracts+`  - label: 01-simple
+    script-hash: '0x0000000000000000000000000000000000030201'
+    generate-sdk: true
+    download: false
+    on-chain:
+      languages:
+        - go
+        - python
+`+networks, string(data))
+	})
+
+	t.Run("should not add a contract twice", func(t *testing.T) {
+		err := run("add", scriptHash.StringLE())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is already in")
+	})
+
+	t.Run("should reject unsupported languages", func(t *testing.T) {
+		err := run("add", "--offchain", "csharp", "0x76a8f8a7a901b29a33013b469949f4b08db15757")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "language 'csharp' is not supported for off-chain SDKs")
+	})
+
+	t.Run("should remove the contract and its SDKs", func(t *testing.T) {
+		goSdk := filepath.Join(dir, "cpm_out", "onchain", "go", "01-simple.go")
+		pythonSdk := filepath.Join(dir, "cpm_out", "onchain", "python", "01-simple")
+		otherSdk := filepath.Join(dir, "cpm_out", "onchain", "go", "other.go")
+		require.NoError(t, os.MkdirAll(pythonSdk, 0755))
+		require.NoError(t, os.MkdirAll(filepath.Dir(goSdk), 0755))
+		require.NoError(t, os.WriteFile(goSdk, nil, 0644))
+		require.NoError(t, os.WriteFile(otherSdk, nil, 0644))
+
+		require.NoError(t, run("remove", "01-simple"))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, config, string(data))
+		assert.NoFileExists(t, goSdk)
+		assert.NoDirExists(t, pythonSdk)
+		assert.FileExists(t, otherSdk)
+	})
+}
+
+func Test_RemoveGeneratedSDKs(t *testing.T) {
+	dir := t.TempDir()
+	saved := cfg
+	cfg = &CPMConfig{path: filepath.Join(dir, DEFAULT_CONFIG_FILE)}
+	t.Cleanup(func() { cfg = saved })
+
+	destination := filepath.Join(dir, "cpm_out", "offchain", "python")
+	otherSdk := filepath.Join(destination, "Other")
+	require.NoError(t, os.MkdirAll(otherSdk, 0755))
+
+	for _, name := range []string{"", "..", "../python", "/tmp"} {
+		t.Run(fmt.Sprintf("should not remove anything for sdk-name '%s'", name), func(t *testing.T) {
+			c := &ContractConfig{Label: "A", SdkName: &name, OffChain: &GenerateConfig{Languages: []string{LANG_PYTHON}}}
+			removeGeneratedSDKs(c)
+			assert.DirExists(t, otherSdk)
+		})
+	}
+
+	t.Run("should only allow paths inside the destination", func(t *testing.T) {
+		assert.True(t, isBelow("out/", "out/sdk.go"))
+		assert.False(t, isBelow("out/", "out/"))
+		assert.False(t, isBelow("out/", "out/../sdk.go"))
+		assert.False(t, isBelow("out/", ""))
+	})
+}
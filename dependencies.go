@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// dependency describes a contract that is called by another contract
+type dependency struct {
+	ScriptHash util.Uint160
+	Label      string
+	// the contract that caused this dependency to be discovered
+	Parent util.Uint160
+}
+
+// contractDependencies returns the script hashes of all contracts the given contract can call according to its NEF
+// method tokens (CALLT targets) and the manifest permissions that specify a concrete contract hash
+func contractDependencies(contractState *state.Contract) []util.Uint160 {
+	seen := make(map[util.Uint160]bool)
+	var deps []util.Uint160
+
+	add := func(h util.Uint160) {
+		if h.Equals(contractState.Hash) || seen[h] {
+			return
+		}
+		seen[h] = true
+		deps = append(deps, h)
+	}
+
+	for _, token := range contractState.NEF.Tokens {
+		add(token.Hash)
+	}
+
+	for _, permission := range contractState.Manifest.Permissions {
+		if permission.Contract.Type == manifest.PermissionHash {
+			add(permission.Contract.Hash())
+		}
+	}
+	return deps
+}
+
+// resolveDependencies recursively discovers the dependencies of the given contracts. Native contracts are skipped as
+// they exist on every network, as are contracts that the source network does not know
+func resolveDependencies(scriptHashes []util.Uint160, hosts []string, height *uint32) ([]dependency, error) {
+	seen := make(map[util.Uint160]bool)
+	for _, h := range scriptHashes {
+		seen[h] = true
+	}
+
+	var result []dependency
+	queue := append([]util.Uint160{}, scriptHashes...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		contractState, err := fetchContract(current, hosts, height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dependencies of contract %s: %w", current.StringLE(), err)
+		}
+
+		for _, h := range contractDependencies(contractState) {
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+
+			depState, err := fetchContract(h, hosts, height)
+			if err != nil {
+				log.Debugf("Skipping dependency %s of contract %s: %v", h.StringLE(), current.StringLE(), err)
+				continue
+			}
+			if depState.ID < 0 {
+				log.Debugf("Skipping dependency %s of contract %s: native contract '%s'", h.StringLE(), current.StringLE(), depState.Manifest.Name)
+				continue
+			}
+
+			log.Infof("Found dependency '%s' (%s) of contract %s", depState.Manifest.Name, h.StringLE(), current.StringLE())
+			result = append(result, dependency{ScriptHash: h, Label: depState.Manifest.Name, Parent: current})
+			queue = append(queue, h)
+		}
+	}
+	return result, nil
+}
+
+// fetchContract returns the contract state from the first host that responds successfully
+func fetchContract(scriptHash util.Uint160, hosts []string, height *uint32) (*state.Contract, error) {
+	var contractState *state.Contract
+	err := tryHosts(hosts, log.DebugLevel, func(host string) error {
+		contract, err := loadContract(scriptHash, host, height, false, "contract state")
+		if err != nil {
+			return err
+		}
+		contractState = contract.State
+		return nil
+	})
+	return contractState, err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/native/nativehashes"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ContractDependencies(t *testing.T) {
+	var res struct {
+		Result state.Contract `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(contractStateResult), &res))
+	contractState := &res.Result
+
+	t.Run("method tokens and permissions are deduplicated", func(t *testing.T) {
+		// the contract has a method token and a permission for ContractManagement
+		deps := contractDependencies(contractState)
+		assert.Equal(t, []util.Uint160{nativehashes.ContractManagement}, deps)
+	})
+
+	t.Run("wildcard and self permissions are ignored", func(t *testing.T) {
+		other := util.Uint160{1, 2, 3}
+		contractState.NEF.Tokens = nil
+		contractState.Manifest.Permissions = []manifest.Permission{
+			*manifest.NewPermission(manifest.PermissionWildcard),
+			*manifest.NewPermission(manifest.PermissionHash, contractState.Hash),
+			*manifest.NewPermission(manifest.PermissionHash, other),
+		}
+		deps := contractDependencies(contractState)
+		assert.Equal(t, []util.Uint160{other}, deps)
+	})
+}
@@ -2,25 +2,96 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/nspcc-dev/neo-go/pkg/util"
-	log "github.com/sirupsen/logrus"
+	"math/big"
+	"net/http"
+	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/nspcc-dev/neo-go/pkg/config"
+	"github.com/nspcc-dev/neo-go/pkg/core"
+	"github.com/nspcc-dev/neo-go/pkg/core/dao"
+	"github.com/nspcc-dev/neo-go/pkg/core/mpt"
+	"github.com/nspcc-dev/neo-go/pkg/core/native"
+	"github.com/nspcc-dev/neo-go/pkg/core/native/nativehashes"
+	"github.com/nspcc-dev/neo-go/pkg/core/native/nativeids"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/stateroot"
+	"github.com/nspcc-dev/neo-go/pkg/core/storage"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/zap"
 )
 
 type Downloader interface {
-	downloadContract(scriptHash util.Uint160, host string) (string, error)
+	// downloadContract downloads the contract from the host to the local chain. If height is nil the latest state is used
+	downloadContract(scriptHash util.Uint160, host string, height *uint32) (string, error)
+}
+
+// downloadError is the error of a failed download. It reads as the message of the downloader and wraps the cause
+type downloadError struct {
+	message string
+	err     error
+}
+
+func (e *downloadError) Error() string { return e.message }
+func (e *downloadError) Unwrap() error { return e.err }
+
+const (
+	DESTINATION_NEO_EXPRESS = "neo-express"
+	DESTINATION_NEO_GO      = "neo-go"
+
+	DOWNLOADER_NEOXP  = "neoxp"
+	DOWNLOADER_NATIVE = "native"
+)
+
+// NewDownloader returns the Downloader for the given contract destination. For neo-express the 'downloader' key of the
+// neo-express tool config selects the implementation
+func NewDownloader(destination, expressConfigPath string) Downloader {
+	switch destination {
+	case "", DESTINATION_NEO_EXPRESS:
+		switch cfg.Tools.NeoExpress.Downloader {
+		case "", DOWNLOADER_NEOXP:
+			if offline {
+				log.Fatalf("The '%s' downloader fetches contracts itself and can't be used offline. Set 'downloader: %s' "+
+					"for neo-express in cpm.yaml", DOWNLOADER_NEOXP, DOWNLOADER_NATIVE)
+			}
+			return NewNeoExpressDownloader(expressConfigPath)
+		case DOWNLOADER_NATIVE:
+			return NewNativeNeoExpressDownloader(expressConfigPath)
+		default:
+			log.Fatalf("Unknown neo-express downloader '%s'. Valid values are '%s' and '%s'", cfg.Tools.NeoExpress.Downloader,
+				DOWNLOADER_NEOXP, DOWNLOADER_NATIVE)
+		}
+	case DESTINATION_NEO_GO:
+		return NewNeoGoDownloader()
+	default:
+		log.Fatalf("Unknown contract destination '%s'. Valid values are '%s' and '%s'", destination,
+			DESTINATION_NEO_EXPRESS, DESTINATION_NEO_GO)
+	}
+	return nil
 }
 
 type NeoExpressDownloader struct {
 	expressConfigPath *string
+	// neoxp modifies the neo-express chain, so only one download can run at a time
+	mu sync.Mutex
 }
 
 func NewNeoExpressDownloader(configPath string) Downloader {
-	executablePath := cfg.Tools.NeoExpress.ExecutablePath
-	if executablePath == nil {
+	if cfg.Tools.NeoExpress.ExecutablePath == nil {
 		var cmd *exec.Cmd
 		if runtime.GOOS == "darwin" {
 			cmd = exec.Command("bash", "-c", "neoxp -h")
@@ -35,7 +106,7 @@ func NewNeoExpressDownloader(configPath string) Downloader {
 		}
 	} else {
 		// Verify path works by calling help (which has a 0 exit code)
-		cmd := exec.Command(*executablePath, "-h")
+		cmd := exec.Command(cfg.resolveExecutable(*cfg.Tools.NeoExpress.ExecutablePath), "-h")
 		err := cmd.Run()
 		if err != nil {
 			log.Fatal(fmt.Errorf("could not find 'neoxp' executable in the configured executable-path: %w", err))
@@ -46,23 +117,45 @@ func NewNeoExpressDownloader(configPath string) Downloader {
 	}
 }
 
-func (ned *NeoExpressDownloader) downloadContract(scriptHash util.Uint160, host string) (string, error) {
+func (ned *NeoExpressDownloader) downloadContract(scriptHash util.Uint160, host string, height *uint32) (string, error) {
+	if isWebSocketHost(host) {
+		err := fmt.Errorf("neoxp does not support WebSocket host %s, use 'downloader: %s' instead", host, DOWNLOADER_NATIVE)
+		return "[NEOXP] " + err.Error(), err
+	}
+
+	// neoxp contacts the host itself, so the network is verified up front
+	if err := verifyHost(host); err != nil {
+		return "[NEOXP] " + err.Error(), err
+	}
+	if network := cfg.networkForHost(host); network != nil && network.hasTransportSettings() {
+		err := fmt.Errorf("neoxp can't apply the headers, proxy and TLS settings of network '%s', use 'downloader: %s' instead",
+			network.Label, DOWNLOADER_NATIVE)
+		return "[NEOXP] " + err.Error(), err
+	}
+
 	// the name and arguments supplied to exec.Command differ slightly depending on the OS and whether neoxp is
 	// installed globally. the following are the base arguments that hold for all scenarios
-	args := []string{"contract", "download", "-i", cfg.Tools.NeoExpress.ConfigPath, "--force", "0x" + scriptHash.StringLE(), host}
+	args := []string{"contract", "download", "-i", *ned.expressConfigPath, "--force"}
+	if height != nil {
+		args = append(args, "--height", strconv.FormatUint(uint64(*height), 10))
+	}
+	args = append(args, "0x"+scriptHash.StringLE(), host)
 
 	// global default
 	executable := "neoxp"
 
-	if cfg.Tools.NeoExpress.ExecutablePath != nil {
-		executable = *cfg.Tools.NeoExpress.ExecutablePath
+	if executablePath := cfg.Tools.NeoExpress.ExecutablePath; executablePath != nil {
+		executable = cfg.resolveExecutable(*executablePath)
 	} else if runtime.GOOS == "darwin" {
 		executable = "bash"
 		tmp := append([]string{"neoxp"}, args...)
 		args = []string{"-c", strings.Join(tmp, " ")}
 	}
 
-	cmd := exec.Command(executable, args...)
+	ned.mu.Lock()
+	defer ned.mu.Unlock()
+
+	cmd := exec.CommandContext(appCtx, executable, args...)
 	var errOut bytes.Buffer
 	cmd.Stderr = &errOut
 	out, err := cmd.Output()
@@ -72,3 +165,382 @@ func (ned *NeoExpressDownloader) downloadContract(scriptHash util.Uint160, host
 		return "[NEOXP]" + string(out), nil
 	}
 }
+
+// NativeNeoExpressDownloader fetches the contract state and storage over RPC itself and persists them into a running
+// neo-express instance, which still has to be started with neoxp. neo-express keeps its chain in RocksDB, which can't be
+// written without native libraries, so unlike neoxp it can't write into a stopped chain or a checkpoint
+type NativeNeoExpressDownloader struct {
+	expressConfigPath string
+	expressRpcHost    string
+	// guards writes to the neo-express chain. Fetching from the source network can run concurrently
+	mu sync.Mutex
+	// running holds the result of checking that the neo-express instance is running
+	running       sync.Once
+	errNotRunning error
+}
+
+func NewNativeNeoExpressDownloader(configPath string) Downloader {
+	host, err := getNeoExpressRpcHost(configPath)
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to read neo-express config: %w", err))
+	}
+	return &NativeNeoExpressDownloader{
+		expressConfigPath: configPath,
+		expressRpcHost:    host,
+	}
+}
+
+func (nd *NativeNeoExpressDownloader) downloadContract(scriptHash util.Uint160, host string, height *uint32) (string, error) {
+	if err := nd.checkRunning(); err != nil {
+		return "[NATIVE] " + err.Error(), err
+	}
+
+	state, storage, err := fetchContractState(scriptHash, host, height)
+	if err != nil {
+		return "[NATIVE] " + err.Error(), err
+	}
+
+	nd.mu.Lock()
+	err = persistContractToNeoExpress(nd.expressRpcHost, state, storage)
+	nd.mu.Unlock()
+	if err != nil {
+		return "[NATIVE] " + err.Error(), err
+	}
+	return fmt.Sprintf("[NATIVE] Downloaded contract '%s' (%s) with %d storage items to %s", state.Manifest.Name,
+		scriptHash.StringLE(), len(storage), nd.expressConfigPath), nil
+}
+
+// checkRunning verifies once that the neo-express instance answers RPC calls, so a stopped chain is reported before any
+// contract is fetched
+func (nd *NativeNeoExpressDownloader) checkRunning() error {
+	nd.running.Do(func() {
+		var version json.RawMessage
+		if err := rpcCall(nd.expressRpcHost, "getversion", []any{}, &version); err != nil {
+			// %v, so that the connection error is not taken for an error of the source host
+			nd.errNotRunning = fmt.Errorf("neo-express is not running at %s. The '%s' downloader writes contracts into a "+
+				"running instance, start it with 'neoxp run -i %s' or use 'downloader: %s' to write into the stopped chain: %v",
+				nd.expressRpcHost, DOWNLOADER_NATIVE, nd.expressConfigPath, DOWNLOADER_NEOXP, err)
+		}
+	})
+	return nd.errNotRunning
+}
+
+// NeoGoDownloader writes the contract state and storage straight into the database of a neo-go private network, so the
+// contract keeps the script hash and storage it has on the source network. neo-go does not allow to write contract
+// storage over RPC, therefore the node must be stopped while contracts are downloaded
+type NeoGoDownloader struct {
+	chainConfig config.Config
+	// the database can only be opened once at a time, so downloads are serialized
+	mu sync.Mutex
+}
+
+func NewNeoGoDownloader() Downloader {
+	configPath := cfg.Tools.NeoGo.ConfigPath
+	if configPath == "" {
+		log.Fatal("'config-path' must be set in the neo-go tools section of cpm.yaml")
+	}
+
+	// relative database paths in the node config are resolved like the node does when started from the directory of cpm.yaml
+	chainConfig, err := config.LoadFile(cfg.resolvePath(configPath), cfg.resolvePath("."))
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to read neo-go node config: %w", err))
+	}
+	return &NeoGoDownloader{
+		chainConfig: chainConfig,
+	}
+}
+
+func (nd *NeoGoDownloader) downloadContract(scriptHash util.Uint160, host string, height *uint32) (string, error) {
+	contractState, storage, err := fetchContractState(scriptHash, host, height)
+	if err != nil {
+		return "[NEOGO] " + err.Error(), err
+	}
+
+	nd.mu.Lock()
+	err = persistContractToNeoGo(nd.chainConfig, contractState, storage)
+	nd.mu.Unlock()
+	if err != nil {
+		return "[NEOGO] " + err.Error(), err
+	}
+	return fmt.Sprintf("[NEOGO] Downloaded contract '%s' (%s) with %d storage items to %s", contractState.Manifest.Name,
+		scriptHash.StringLE(), len(storage), cfg.Tools.NeoGo.ConfigPath), nil
+}
+
+// fetchContractState returns the contract state and its complete storage from the given host. If height is set the
+// state is taken from the MPT state root at that block height instead of the latest state
+func fetchContractState(scriptHash util.Uint160, host string, height *uint32) (*state.Contract, []result.KeyValue, error) {
+	contract, err := loadContract(scriptHash, host, height, true, "contract state")
+	if err != nil {
+		return nil, nil, err
+	}
+	return contract.State, contract.Storage, nil
+}
+
+// fetchStorage returns the complete latest storage of the contract
+func fetchStorage(client *rpcclient.Client, scriptHash util.Uint160) ([]result.KeyValue, error) {
+	// not nil, so that an empty storage is cached as well
+	storage := []result.KeyValue{}
+	start := 0
+	for {
+		res, err := client.FindStorageByHash(scriptHash, nil, &start)
+		if err != nil {
+			return nil, fmt.Errorf("findstorage failed with: %w", err)
+		}
+		storage = append(storage, res.Results...)
+		if !res.Truncated {
+			break
+		}
+		start = res.Next
+	}
+	return storage, nil
+}
+
+// fetchHistoricContractState returns the contract state, and the storage if withStorage is set, as they were at the
+// given block height using getstate/findstates. The source node must keep historic state (i.e. have the StateRoot
+// service enabled)
+func fetchHistoricContractState(client *rpcclient.Client, scriptHash util.Uint160, height uint32, withStorage bool) (*state.Contract, []result.KeyValue, error) {
+	root, err := client.GetStateRootByHeight(height)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getstateroot failed with: %w", err)
+	}
+
+	contractState, err := getHistoricContractState(client, root.Root, scriptHash)
+	if err != nil || !withStorage {
+		return contractState, nil, err
+	}
+
+	storage := []result.KeyValue{}
+	var start []byte
+	for {
+		res, err := client.FindStates(root.Root, scriptHash, nil, start, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("findstates failed with: %w", err)
+		}
+		storage = append(storage, res.Results...)
+		if !res.Truncated || len(res.Results) == 0 {
+			break
+		}
+		start = res.Results[len(res.Results)-1].Key
+	}
+	return contractState, storage, nil
+}
+
+// getContractState returns the contract state at the given block height, or the latest state if height is nil
+func getContractState(client *rpcclient.Client, scriptHash util.Uint160, height *uint32) (*state.Contract, error) {
+	if height == nil {
+		contractState, err := client.GetContractStateByHash(scriptHash)
+		if err != nil {
+			return nil, fmt.Errorf("getcontractstate failed with: %w", err)
+		}
+		return contractState, nil
+	}
+
+	root, err := client.GetStateRootByHeight(*height)
+	if err != nil {
+		return nil, fmt.Errorf("getstateroot failed with: %w", err)
+	}
+	return getHistoricContractState(client, root.Root, scriptHash)
+}
+
+// getHistoricContractState reads the contract state from the ContractManagement storage at the given state root
+func getHistoricContractState(client *rpcclient.Client, root util.Uint256, scriptHash util.Uint160) (*state.Contract, error) {
+	// contract states are stored by ContractManagement under the contract prefix (8) followed by the script hash
+	key := append([]byte{8}, scriptHash.BytesBE()...)
+	raw, err := client.GetState(root, nativehashes.ContractManagement, key)
+	if err != nil {
+		return nil, fmt.Errorf("getstate failed with: %w", err)
+	}
+
+	item, err := stackitem.Deserialize(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize contract state: %v", err)
+	}
+
+	contractState := new(state.Contract)
+	err = contractState.FromStackItem(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode contract state: %v", err)
+	}
+	return contractState, nil
+}
+
+// getNeoExpressRpcHost returns the RPC address of the first consensus node in the neo-express config file
+func getNeoExpressRpcHost(configPath string) (string, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	expressConfig := struct {
+		ConsensusNodes []struct {
+			RpcPort uint16 `json:"rpc-port"`
+		} `json:"consensus-nodes"`
+	}{}
+	err = json.Unmarshal(data, &expressConfig)
+	if err != nil {
+		return "", err
+	}
+
+	if len(expressConfig.ConsensusNodes) != 1 {
+		return "", fmt.Errorf("contract download is only supported for single node neo-express chains, found %d nodes",
+			len(expressConfig.ConsensusNodes))
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", expressConfig.ConsensusNodes[0].RpcPort), nil
+}
+
+// persistContractToNeoExpress writes the contract state and storage into a running neo-express node. This is the same
+// RPC call `neoxp contract download --force` uses when the chain is running
+func persistContractToNeoExpress(expressHost string, contractState *state.Contract, storage []result.KeyValue) error {
+	type storagePair struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	pairs := make([]storagePair, 0, len(storage))
+	for _, kv := range storage {
+		pairs = append(pairs, storagePair{
+			Key:   base64.StdEncoding.EncodeToString(kv.Key),
+			Value: base64.StdEncoding.EncodeToString(kv.Value),
+		})
+	}
+
+	params := map[string]any{
+		"state":   contractState,
+		"storage": pairs,
+		"force":   "All",
+	}
+
+	var res json.RawMessage
+	err := rpcCall(expressHost, "expresspersistcontract", []any{params}, &res)
+	if err != nil {
+		// %v, so that errors of the local node are not taken for errors of the source host
+		return fmt.Errorf("failed to persist contract to neo-express at %s: %v", expressHost, err)
+	}
+	return nil
+}
+
+// keyNextAvailableID is the ContractManagement storage key of the ID the next deployed contract gets
+var keyNextAvailableID = []byte{15}
+
+// persistContractToNeoGo writes the contract state and storage into the database of the neo-go node described by
+// chainConfig. A contract with the same script hash is replaced. The changes are added to the MPT of the current block,
+// so the local state root keeps matching the storage
+func persistContractToNeoGo(chainConfig config.Config, contractState *state.Contract, storageItems []result.KeyValue) error {
+	store, err := storage.NewStore(chainConfig.ApplicationConfiguration.DBConfiguration)
+	if err != nil {
+		return fmt.Errorf("failed to open neo-go database (stop the node before downloading contracts): %w", err)
+	}
+	defer store.Close()
+
+	d := dao.NewSimple(store, chainConfig.ProtocolConfiguration.StateRootInHeader)
+	d.Version, err = d.GetVersion()
+	if err != nil {
+		return errors.New("the neo-go database does not contain a chain, start the node once to create it")
+	}
+	// opening the chain verifies that the database was written by a compatible neo-go version with the same settings
+	if _, err := core.NewBlockchain(store, chainConfig.Blockchain(), zap.NewNop()); err != nil {
+		return fmt.Errorf("failed to open neo-go chain: %w", err)
+	}
+	height, err := d.GetCurrentBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to read neo-go block height: %w", err)
+	}
+
+	cache := d.GetPrivate()
+	cs := *contractState
+	cs.ID, err = localContractID(cache, cs.Hash)
+	if err != nil {
+		return err
+	}
+	err = cache.PutStorageConvertible(nativeids.ContractManagement, native.MakeContractKey(cs.Hash), &cs)
+	if err != nil {
+		return fmt.Errorf("failed to write contract state: %w", err)
+	}
+	for _, kv := range storageItems {
+		cache.PutStorageItem(cs.ID, kv.Key, kv.Value)
+	}
+
+	stateRoot := stateroot.NewModule(chainConfig.Blockchain(), nil, zap.NewNop(), d.Store)
+	if err := stateRoot.Init(height); err != nil {
+		return fmt.Errorf("failed to read local state root: %w", err)
+	}
+	_, _, err = stateRoot.AddMPTBatch(height, mpt.MapToMPTBatch(cache.Store.GetStorageChanges()), cache.Store)
+	if err != nil {
+		return fmt.Errorf("failed to update local state root: %w", err)
+	}
+
+	if _, err := cache.Persist(); err != nil {
+		return err
+	}
+	_, err = d.Persist()
+	return err
+}
+
+// localContractID returns the ID of the contract in the local chain. The storage of an existing contract is removed,
+// otherwise the next free ID is allocated like ContractManagement does on deployment
+func localContractID(d *dao.Simple, hash util.Uint160) (int32, error) {
+	existing := new(state.Contract)
+	err := d.GetStorageConvertible(nativeids.ContractManagement, native.MakeContractKey(hash), existing)
+	if err == nil {
+		if existing.ID < 0 {
+			return 0, fmt.Errorf("'%s' is a native contract and can't be replaced", existing.Manifest.Name)
+		}
+		var keys [][]byte
+		d.Seek(existing.ID, storage.SeekRange{}, func(k, _ []byte) bool {
+			keys = append(keys, bytes.Clone(k))
+			return true
+		})
+		for _, k := range keys {
+			d.DeleteStorageItem(existing.ID, k)
+		}
+		return existing.ID, nil
+	}
+	if !errors.Is(err, storage.ErrKeyNotFound) {
+		return 0, fmt.Errorf("failed to read local contract state: %w", err)
+	}
+
+	id, err := d.GetInt(nativeids.ContractManagement, keyNextAvailableID)
+	if err != nil {
+		return 0, err
+	}
+	d.PutBigInt(nativeids.ContractManagement, keyNextAvailableID, big.NewInt(id+1))
+	// ContractManagement indexes contract hashes by ID under prefix 12
+	key := binary.BigEndian.AppendUint32([]byte{12}, uint32(id))
+	d.PutStorageItem(nativeids.ContractManagement, key, hash.BytesBE())
+	return int32(id), nil
+}
+
+// rpcCall performs a JSON-RPC request for methods that are not covered by the neo-go RPC client
+func rpcCall(host, method string, params []any, v any) error {
+	req := neorpc.Request{
+		JSONRPC: neorpc.JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+		ID:      1,
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(appCtx, http.MethodPost, host, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw := new(neorpc.Response)
+	err = json.NewDecoder(resp.Body).Decode(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if raw.Error != nil {
+		return raw.Error
+	}
+	return json.Unmarshal(raw.Result, v)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/config"
+	"github.com/nspcc-dev/neo-go/pkg/config/netmode"
+	"github.com/nspcc-dev/neo-go/pkg/core"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/storage"
+	"github.com/nspcc-dev/neo-go/pkg/core/storage/dbconfig"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_NativeNeoExpressDownloader(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := util.Uint160{}
+
+	t.Run("should persist contract state and storage", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{
+			{"getcontractstate", contractStateResult},
+			{"findstorage", `{"jsonrpc":"2.0","id":1,"result":{"results":[{"key":"AQ==","value":"Ag=="}],"next":1,"truncated":true}}`},
+			{"findstorage", `{"jsonrpc":"2.0","id":1,"result":{"results":[{"key":"Aw==","value":"BA=="}],"next":2,"truncated":false}}`},
+		})
+		defer srv.Close()
+
+		var persisted JsonRPC
+		express := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &persisted)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":1}`))
+		}))
+		defer express.Close()
+
+		d := &NativeNeoExpressDownloader{expressConfigPath: "default.neo-express", expressRpcHost: express.URL}
+		message, err := d.downloadContract(c, srv.URL, nil)
+		require.NoError(t, err, message)
+		assert.Contains(t, message, "with 2 storage items")
+
+		assert.Equal(t, "expresspersistcontract", persisted.Method)
+		if assert.Len(t, persisted.Params, 1) {
+			params := persisted.Params[0].(map[string]interface{})
+			assert.Equal(t, "All", params["force"])
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"key": "AQ==", "value": "Ag=="},
+				map[string]interface{}{"key": "Aw==", "value": "BA=="},
+			}, params["storage"])
+		}
+	})
+
+	t.Run("should report a neo-express instance that is not running", func(t *testing.T) {
+		express := httptest.NewServer(http.NotFoundHandler())
+		express.Close()
+
+		d := &NativeNeoExpressDownloader{expressConfigPath: "default.neo-express", expressRpcHost: express.URL}
+		_, err := d.downloadContract(c, "http://127.0.0.1:0", nil)
+		assert.ErrorContains(t, err, "neo-express is not running at "+express.URL)
+	})
+
+	t.Run("should fetch contract state and storage at height", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{
+			{"getstateroot", stateRootResult},
+			{"getstate", historicContractStateResult(t)},
+			{"findstates", `{"jsonrpc":"2.0","id":1,"result":{"results":[{"key":"AQ==","value":"Ag=="}],"truncated":true}}`},
+			{"findstates", `{"jsonrpc":"2.0","id":1,"result":{"results":[{"key":"Aw==","value":"BA=="}],"truncated":false}}`},
+		})
+		defer srv.Close()
+
+		height := uint32(5)
+		contractState, storage, err := fetchContractState(c, srv.URL, &height)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", contractState.Manifest.Name)
+		assert.Len(t, storage, 2)
+	})
+
+	t.Run("should read rpc port from neo-express config", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "default.neo-express")
+		err := os.WriteFile(configPath, []byte(`{"magic":1,"consensus-nodes":[{"tcp-port":50011,"ws-port":50012,"rpc-port":50013}]}`), 0644)
+		require.NoError(t, err)
+
+		host, err := getNeoExpressRpcHost(configPath)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("http://127.0.0.1:%d", 50013), host)
+	})
+}
+
+func Test_NeoGoDownloader(t *testing.T) {
+	chainConfig, err := config.Load(config.DefaultConfigPath, netmode.PrivNet)
+	require.NoError(t, err)
+	chainConfig.ApplicationConfiguration.DBConfiguration = dbconfig.DBConfiguration{
+		Type:          dbconfig.BoltDB,
+		BoltDBOptions: dbconfig.BoltDBOptions{FilePath: filepath.Join(t.TempDir(), "chain.bolt")},
+	}
+
+	// openChain opens the chain and returns it with a function that persists and closes it again
+	openChain := func(t *testing.T) (*core.Blockchain, func()) {
+		store, err := storage.NewStore(chainConfig.ApplicationConfiguration.DBConfiguration)
+		require.NoError(t, err)
+		bc, err := core.NewBlockchain(store, chainConfig.Blockchain(), zap.NewNop())
+		require.NoError(t, err)
+		go bc.Run()
+		return bc, bc.Close
+	}
+	_, closeChain := openChain(t)
+	closeChain()
+
+	var res struct {
+		Result state.Contract `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(contractStateResult), &res))
+	contractState := &res.Result
+
+	t.Run("should write contract state and storage under the source script hash", func(t *testing.T) {
+		err := persistContractToNeoGo(chainConfig, contractState, []result.KeyValue{
+			{Key: []byte{1}, Value: []byte{2}},
+			{Key: []byte{3}, Value: []byte{4}},
+		})
+		require.NoError(t, err)
+
+		bc, closeChain := openChain(t)
+		defer closeChain()
+		local := bc.GetContractState(contractState.Hash)
+		require.NotNil(t, local)
+		assert.Equal(t, contractState.Manifest.Name, local.Manifest.Name)
+		assert.Equal(t, []byte{2}, []byte(bc.GetStorageItem(local.ID, []byte{1})))
+		assert.Equal(t, []byte{4}, []byte(bc.GetStorageItem(local.ID, []byte{3})))
+
+		// the storage is part of the local state root
+		module := bc.GetStateModule()
+		value, err := module.GetState(module.CurrentLocalStateRoot(), append(binary.LittleEndian.AppendUint32(nil, uint32(local.ID)), 1))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{2}, value)
+	})
+
+	t.Run("should replace the storage of a downloaded contract", func(t *testing.T) {
+		err := persistContractToNeoGo(chainConfig, contractState, []result.KeyValue{{Key: []byte{3}, Value: []byte{5}}})
+		require.NoError(t, err)
+
+		bc, closeChain := openChain(t)
+		defer closeChain()
+		local := bc.GetContractState(contractState.Hash)
+		require.NotNil(t, local)
+		assert.Nil(t, bc.GetStorageItem(local.ID, []byte{1}))
+		assert.Equal(t, []byte{5}, []byte(bc.GetStorageItem(local.ID, []byte{3})))
+	})
+
+	t.Run("should fail while the node is running", func(t *testing.T) {
+		_, closeChain := openChain(t)
+		defer closeChain()
+
+		err := persistContractToNeoGo(chainConfig, contractState, nil)
+		assert.ErrorContains(t, err, "stop the node")
+	})
+}
+
+const stateRootResult = `{"jsonrpc":"2.0","id":1,"result":{"version":0,"index":5,"roothash":"0x5ac7ed5c1bbc5b0d8dd2f3aa1d2d1a0f3a1ef0b2b5ba19d4e7e3c1a1f2a6f1e9","witnesses":[]}}`
+
+// historicContractStateResult returns the getstate response with the contract state of contractStateResult as it is
+// stored by ContractManagement
+func historicContractStateResult(t *testing.T) string {
+	var res struct {
+		Result state.Contract `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(contractStateResult), &res))
+	item, err := res.Result.ToStackItem()
+	require.NoError(t, err)
+	raw, err := stackitem.Serialize(item)
+	require.NoError(t, err)
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":"%s"}`, base64.StdEncoding.EncodeToString(raw))
+}
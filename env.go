@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DOTENV_FILE = ".env"
+
+// envPattern matches ${VAR} and ${VAR:-default}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// loadDotEnv reads KEY=VALUE lines from the file. Empty lines, comments and an 'export ' prefix are ignored and values
+// can be quoted. Returns an empty map if the file does not exist
+func loadDotEnv(path string) (map[string]string, error) {
+	vars := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vars, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNr)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	return vars, scanner.Err()
+}
+
+// envLookup returns a lookup function that prefers the process environment over the variables of the .env file next to
+// the config file
+func envLookup(configPath string) (func(string) (string, bool), error) {
+	dotenv, err := loadDotEnv(filepath.Join(filepath.Dir(configPath), DOTENV_FILE))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DOTENV_FILE, err)
+	}
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := dotenv[name]
+		return value, ok
+	}, nil
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} in s. An unset variable without default is an error
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	var err error
+	result := envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := envPattern.FindStringSubmatch(match)
+		if value, ok := lookup(groups[1]); ok && (value != "" || groups[2] == "") {
+			return value
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("environment variable '%s' is not set", groups[1])
+		}
+		return match
+	})
+	return result, err
+}
+
+// interpolateNode interpolates all scalar values in the YAML tree
+func interpolateNode(node *yaml.Node, lookup func(string) (string, bool)) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		value, err := interpolate(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		// the value may no longer be a string, i.e. 'magic: ${MAGIC}'
+		node.Tag = ""
+	case yaml.MappingNode:
+		// only values are interpolated, keys are left as they are
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := interpolateNode(node.Content[i], lookup); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := interpolateNode(child, lookup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
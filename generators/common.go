@@ -1,13 +1,17 @@
 package generators
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
 	"github.com/nspcc-dev/neo-go/pkg/util"
 )
@@ -22,43 +26,71 @@ const (
 
 type (
 	GenerateCfg struct {
-		Manifest              *manifest.Manifest
-		ContractHash          util.Uint160
-		ContractOutput        *os.File
-		ParamTypeConverter    convertParam
-		MethodNameConverter   func(s string) string
-		SdkDestination        string
+		Manifest     *manifest.Manifest
+		ContractHash util.Uint160
+		// ContractName is the name of the generated class, package or module. The manifest name is used if it is empty
+		ContractName        string
+		ContractOutput      *os.File
+		ParamTypeConverter  convertParam
+		MethodNameConverter func(s string) string
+		SdkDestination      string
+		// TemplateDir is the directory with templates that override the built-in templates. Empty to use the built-in
+		TemplateDir           string
 		SupportMethodOverload bool
+		// Bindings is a neo-go binding config, i.e. written by 'neo-go contract compile --bindings'. Its named types and
+		// extended types of methods and events give structs, typed arrays and typed maps. nil if there is none
+		Bindings *binding.Config
+		// StructTypeConverter, ArrayTypeConverter and MapTypeConverter convert the extended types of Bindings. Types
+		// without a converter are converted by ParamTypeConverter
+		StructTypeConverter func(name string) string
+		ArrayTypeConverter  func(value string) string
+		MapTypeConverter    func(key, value string) string
+		// Context stops generators that run external processes when it is cancelled, i.e. when the user interrupts cpm.
+		// context.Background() is used if it is nil
+		Context context.Context
 	}
 
+	// ContractTmpl is the contract passed to the SDK templates. It is also sent to generator plugins as JSON
 	ContractTmpl struct {
-		ContractName string
-		Imports      []string
-		Hash         string
-		Methods      []methodTmpl
-		Events       []eventTmpl
+		ContractName string       `json:"contractName"`
+		Imports      []string     `json:"imports"`
+		Hash         string       `json:"hash"`
+		Methods      []methodTmpl `json:"methods"`
+		Events       []eventTmpl  `json:"events"`
+		// Structs are the named types of the bindings, a struct comes after the structs of its fields
+		Structs []structTmpl `json:"structs"`
 	}
 
 	methodTmpl struct {
-		Name          string
-		NameABI       string
-		Comment       string
-		Safe          bool
-		Arguments     []paramTmpl
-		ReturnType    string
-		ReturnTypeABI string
+		Name          string      `json:"name"`
+		NameABI       string      `json:"nameABI"`
+		Comment       string      `json:"comment"`
+		Safe          bool        `json:"safe"`
+		Arguments     []paramTmpl `json:"arguments"`
+		ReturnType    string      `json:"returnType"`
+		ReturnTypeABI string      `json:"returnTypeABI"`
+		// ReturnExtendedType is the extended type of the return value in the bindings, nil if there is none
+		ReturnExtendedType *binding.ExtendedType `json:"returnExtendedType,omitempty"`
 	}
 
 	eventTmpl struct {
-		Name      string
-		NameABI   string
-		Arguments []paramTmpl
+		Name      string      `json:"name"`
+		NameABI   string      `json:"nameABI"`
+		Arguments []paramTmpl `json:"arguments"`
+	}
+
+	structTmpl struct {
+		Name    string      `json:"name"`
+		NameABI string      `json:"nameABI"`
+		Fields  []paramTmpl `json:"fields"`
 	}
 
 	paramTmpl struct {
-		Name    string
-		Type    string
-		TypeABI string
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		TypeABI string `json:"typeABI"`
+		// ExtendedType is the extended type of the parameter in the bindings, nil if there is none
+		ExtendedType *binding.ExtendedType `json:"extendedType,omitempty"`
 	}
 
 	convertParam func(typ smartcontract.ParamType) string
@@ -66,7 +98,7 @@ type (
 
 func TemplateFromManifest(cfg *GenerateCfg) (ContractTmpl, error) {
 	ctr := ContractTmpl{
-		ContractName: cleanContractName(cfg.Manifest.Name),
+		ContractName: cleanContractName(cfg.Name()),
 		Hash:         "0x" + cfg.ContractHash.StringLE(),
 	}
 
@@ -105,15 +137,18 @@ func TemplateFromManifest(cfg *GenerateCfg) (ContractTmpl, error) {
 				name = fmt.Sprintf("arg%d", i)
 			}
 
-			var typeStr = cfg.ParamTypeConverter(method.Parameters[i].Type)
+			et := cfg.extendedType(method.Name + "." + method.Parameters[i].Name)
+			var typeStr = cfg.convertType(method.Parameters[i].Type, et)
 
 			mtd.Arguments = append(mtd.Arguments, paramTmpl{
-				Name:    name,
-				Type:    typeStr,
-				TypeABI: smartcontract.ParamType.String(method.Parameters[i].Type),
+				Name:         name,
+				Type:         typeStr,
+				TypeABI:      smartcontract.ParamType.String(method.Parameters[i].Type),
+				ExtendedType: et,
 			})
 		}
-		mtd.ReturnType = cfg.ParamTypeConverter(method.ReturnType)
+		mtd.ReturnExtendedType = cfg.extendedType(method.Name)
+		mtd.ReturnType = cfg.convertType(method.ReturnType, mtd.ReturnExtendedType)
 		mtd.ReturnTypeABI = smartcontract.ParamType.String(method.ReturnType)
 		ctr.Methods = append(ctr.Methods, mtd)
 	}
@@ -122,7 +157,8 @@ func TemplateFromManifest(cfg *GenerateCfg) (ContractTmpl, error) {
 		name := event.Name
 
 		evt := eventTmpl{
-			Name: name,
+			Name:    name,
+			NameABI: name,
 		}
 
 		for i := range event.Parameters {
@@ -131,23 +167,148 @@ func TemplateFromManifest(cfg *GenerateCfg) (ContractTmpl, error) {
 				name = fmt.Sprintf("arg%d", i)
 			}
 
-			var typeStr = cfg.ParamTypeConverter(event.Parameters[i].Type)
+			et := cfg.extendedType(event.Name + "." + event.Parameters[i].Name)
+			var typeStr = cfg.convertType(event.Parameters[i].Type, et)
 
 			evt.Arguments = append(evt.Arguments, paramTmpl{
-				Name: name,
-				Type: typeStr,
+				Name:         name,
+				Type:         typeStr,
+				TypeABI:      smartcontract.ParamType.String(event.Parameters[i].Type),
+				ExtendedType: et,
 			})
 		}
 		ctr.Events = append(ctr.Events, evt)
 	}
 
+	ctr.Structs = cfg.structs()
+
 	return ctr, nil
 }
 
+// ConvertEventNames converts the event names to the naming convention of the language. TemplateFromManifest keeps the
+// names of the manifest, because the TypeScript API and generator plugins are built from them
+func (ctr *ContractTmpl) ConvertEventNames(convert func(s string) string) {
+	for i := range ctr.Events {
+		ctr.Events[i].Name = convert(ctr.Events[i].NameABI)
+	}
+}
+
+// extendedType returns the extended type of a method return value ('method'), a method parameter ('method.param') or
+// an event parameter ('event.param') in the bindings
+func (cfg *GenerateCfg) extendedType(key string) *binding.ExtendedType {
+	if cfg.Bindings == nil {
+		return nil
+	}
+	if et, ok := cfg.Bindings.Types[key]; ok {
+		return &et
+	}
+	return nil
+}
+
+// convertType converts the ABI type, or the extended type if it is known and the language has a converter for it
+func (cfg *GenerateCfg) convertType(typ smartcontract.ParamType, et *binding.ExtendedType) string {
+	if et != nil {
+		switch {
+		case et.Base == smartcontract.ArrayType && et.Name != "" && cfg.StructTypeConverter != nil:
+			return cfg.StructTypeConverter(et.Name)
+		case et.Base == smartcontract.ArrayType && et.Value != nil && cfg.ArrayTypeConverter != nil:
+			return cfg.ArrayTypeConverter(cfg.convertType(et.Value.Base, et.Value))
+		case et.Base == smartcontract.MapType && et.Value != nil && cfg.MapTypeConverter != nil:
+			return cfg.MapTypeConverter(cfg.ParamTypeConverter(et.Key), cfg.convertType(et.Value.Base, et.Value))
+		}
+	}
+	return cfg.ParamTypeConverter(typ)
+}
+
+// structs returns the named types of the bindings. They are sorted by name, but the structs used by the fields of a
+// struct come first, so that languages that need a type to be declared before it is used can follow the order
+func (cfg *GenerateCfg) structs() []structTmpl {
+	if cfg.Bindings == nil {
+		return nil
+	}
+	names := slices.Sorted(maps.Keys(cfg.Bindings.NamedTypes))
+	var structs []structTmpl
+	seen := make(map[string]bool)
+	var add func(name string)
+	add = func(name string) {
+		nt, ok := cfg.Bindings.NamedTypes[name]
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, f := range nt.Fields {
+			for et := &f.ExtendedType; et != nil; et = et.Value {
+				add(et.Name)
+			}
+		}
+
+		st := structTmpl{
+			Name:    StructName(name),
+			NameABI: name,
+		}
+		for _, f := range nt.Fields {
+			et := f.ExtendedType
+			st.Fields = append(st.Fields, paramTmpl{
+				Name:         cfg.MethodNameConverter(f.Field),
+				Type:         cfg.convertType(et.Base, &et),
+				TypeABI:      smartcontract.ParamType.String(et.Base),
+				ExtendedType: &et,
+			})
+		}
+		structs = append(structs, st)
+	}
+	for _, name := range names {
+		add(name)
+	}
+	return structs
+}
+
+// Name returns the name of the generated class, package or module
+func (cfg *GenerateCfg) Name() string {
+	if cfg.ContractName != "" {
+		return cfg.ContractName
+	}
+	return cfg.Manifest.Name
+}
+
 func UpperFirst(s string) string {
+	if s == "" {
+		return s
+	}
 	return strings.ToUpper(s[0:1]) + s[1:]
 }
 
+// ExtendedTypeOf returns the extended type, or the ABI type as extended type if there is none, so that the template
+// functions of languages can handle both alike
+func ExtendedTypeOf(typeABI string, et *binding.ExtendedType) binding.ExtendedType {
+	if et != nil {
+		return *et
+	}
+	typ, _ := smartcontract.ParseParamType(typeABI)
+	return binding.ExtendedType{Base: typ}
+}
+
+// Structured reports whether the extended type is a struct, a typed array or a typed map, whose values languages
+// convert with generated code
+func Structured(et *binding.ExtendedType) bool {
+	if et == nil {
+		return false
+	}
+	return et.Base == smartcontract.ArrayType && (et.Name != "" || et.Value != nil) ||
+		et.Base == smartcontract.MapType && et.Value != nil
+}
+
+// StructName returns the class name of a named type of the bindings like neo-go does for Go, i.e. 'LibPoint' for
+// 'lib.Point'
+func StructName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' {
+			return -1
+		}
+		return r
+	}, UpperFirst(name))
+}
+
 func cleanContractName(s string) string {
 	return UpperFirst(regexp.MustCompile(`[\W]+`).ReplaceAllString(s, ""))
 }
@@ -0,0 +1,156 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCfg(m *manifest.Manifest) *GenerateCfg {
+	return &GenerateCfg{
+		Manifest:            m,
+		ParamTypeConverter:  smartcontract.ParamType.String,
+		MethodNameConverter: UpperFirst,
+	}
+}
+
+func Test_TemplateFromManifest(t *testing.T) {
+	m := manifest.NewManifest("my contract")
+	m.ABI.Methods = []manifest.Method{
+		{Name: "_deploy", Parameters: []manifest.Parameter{{Name: "data", Type: smartcontract.AnyType}}},
+		{Name: "transfer", ReturnType: smartcontract.BoolType},
+		{Name: "transfer", Parameters: []manifest.Parameter{{Name: "to", Type: smartcontract.Hash160Type}}},
+		{Name: "transfer", Parameters: []manifest.Parameter{{Name: "", Type: smartcontract.Hash160Type}, {Name: "", Type: smartcontract.IntegerType}}},
+		{Name: "transfer1", Parameters: []manifest.Parameter{{Name: "to", Type: smartcontract.Hash160Type}}},
+	}
+
+	t.Run("should suffix overloaded methods with their parameter count", func(t *testing.T) {
+		ctr, err := TemplateFromManifest(newTestCfg(m))
+		require.NoError(t, err)
+
+		var names, abiNames []string
+		for _, mtd := range ctr.Methods {
+			names = append(names, mtd.Name)
+			abiNames = append(abiNames, mtd.NameABI)
+		}
+		assert.Equal(t, []string{"Transfer", "Transfer_1", "Transfer_2", "Transfer1"}, names)
+		assert.Equal(t, []string{"transfer", "transfer", "transfer", "transfer1"}, abiNames)
+		assert.Equal(t, "Mycontract", ctr.ContractName)
+	})
+
+	t.Run("should keep overloaded names if the language supports overloading", func(t *testing.T) {
+		cfg := newTestCfg(m)
+		cfg.SupportMethodOverload = true
+		ctr, err := TemplateFromManifest(cfg)
+		require.NoError(t, err)
+
+		var names []string
+		for _, mtd := range ctr.Methods {
+			names = append(names, mtd.Name)
+		}
+		assert.Equal(t, []string{"Transfer", "Transfer", "Transfer", "Transfer1"}, names)
+	})
+
+	t.Run("should name unnamed parameters by position", func(t *testing.T) {
+		ctr, err := TemplateFromManifest(newTestCfg(m))
+		require.NoError(t, err)
+
+		args := ctr.Methods[2].Arguments
+		require.Len(t, args, 2)
+		assert.Equal(t, "arg0", args[0].Name)
+		assert.Equal(t, "arg1", args[1].Name)
+		assert.Equal(t, "Integer", args[1].TypeABI)
+	})
+
+	t.Run("should keep event names of the manifest until converted by the language", func(t *testing.T) {
+		em := manifest.NewManifest("test")
+		em.ABI.Events = []manifest.Event{{Name: "transfer_done"}}
+		ctr, err := TemplateFromManifest(newTestCfg(em))
+		require.NoError(t, err)
+		assert.Equal(t, "transfer_done", ctr.Events[0].Name)
+
+		ctr.ConvertEventNames(UpperFirst)
+		assert.Equal(t, "Transfer_done", ctr.Events[0].Name)
+		assert.Equal(t, "transfer_done", ctr.Events[0].NameABI)
+	})
+}
+
+func Test_Structs(t *testing.T) {
+	point := binding.ExtendedType{Base: smartcontract.ArrayType, Name: "lib.Point"}
+	cfg := newTestCfg(manifest.NewManifest("test"))
+	cfg.Bindings = &binding.Config{NamedTypes: map[string]binding.ExtendedType{
+		"a.Shape": {Base: smartcontract.ArrayType, Name: "a.Shape", Fields: []binding.FieldExtendedType{
+			{Field: "points", ExtendedType: binding.ExtendedType{Base: smartcontract.ArrayType, Value: &point}},
+			{Field: "color", ExtendedType: binding.ExtendedType{Base: smartcontract.ArrayType, Name: "z.Color"}},
+		}},
+		"lib.Point": {Base: smartcontract.ArrayType, Name: "lib.Point", Fields: []binding.FieldExtendedType{
+			{Field: "x", ExtendedType: binding.ExtendedType{Base: smartcontract.IntegerType}},
+			{Field: "y", ExtendedType: binding.ExtendedType{Base: smartcontract.IntegerType}},
+		}},
+		"z.Color": {Base: smartcontract.ArrayType, Name: "z.Color", Fields: []binding.FieldExtendedType{
+			{Field: "name", ExtendedType: binding.ExtendedType{Base: smartcontract.StringType}},
+		}},
+		"b.Unused": {Base: smartcontract.ArrayType, Name: "b.Unused"},
+	}}
+
+	t.Run("should order structs by name after the structs of their fields", func(t *testing.T) {
+		var names []string
+		for _, s := range cfg.structs() {
+			names = append(names, s.NameABI)
+		}
+		assert.Equal(t, []string{"lib.Point", "z.Color", "a.Shape", "b.Unused"}, names)
+	})
+
+	t.Run("should convert struct fields", func(t *testing.T) {
+		structs := cfg.structs()
+		assert.Equal(t, "LibPoint", structs[0].Name)
+		assert.Equal(t, []paramTmpl{
+			{Name: "X", Type: "Integer", TypeABI: "Integer", ExtendedType: &binding.ExtendedType{Base: smartcontract.IntegerType}},
+			{Name: "Y", Type: "Integer", TypeABI: "Integer", ExtendedType: &binding.ExtendedType{Base: smartcontract.IntegerType}},
+		}, structs[0].Fields)
+	})
+
+	t.Run("should return no structs without bindings", func(t *testing.T) {
+		assert.Nil(t, newTestCfg(manifest.NewManifest("test")).structs())
+	})
+}
+
+func Test_ConvertType(t *testing.T) {
+	cfg := newTestCfg(manifest.NewManifest("test"))
+	cfg.StructTypeConverter = func(name string) string { return "struct " + StructName(name) }
+	cfg.ArrayTypeConverter = func(value string) string { return "[]" + value }
+	cfg.MapTypeConverter = func(key, value string) string { return "map[" + key + "]" + value }
+
+	point := &binding.ExtendedType{Base: smartcontract.ArrayType, Name: "lib.Point"}
+	for _, tc := range []struct {
+		name string
+		typ  smartcontract.ParamType
+		et   *binding.ExtendedType
+		want string
+	}{
+		{"ABI type", smartcontract.Hash160Type, nil, "Hash160"},
+		{"extended ABI type", smartcontract.IntegerType, &binding.ExtendedType{Base: smartcontract.IntegerType}, "Integer"},
+		{"struct", smartcontract.ArrayType, point, "struct LibPoint"},
+		{"typed array", smartcontract.ArrayType, &binding.ExtendedType{Base: smartcontract.ArrayType, Value: point}, "[]struct LibPoint"},
+		{"nested array", smartcontract.ArrayType, &binding.ExtendedType{Base: smartcontract.ArrayType,
+			Value: &binding.ExtendedType{Base: smartcontract.ArrayType, Value: &binding.ExtendedType{Base: smartcontract.StringType}}}, "[][]String"},
+		{"typed map", smartcontract.MapType, &binding.ExtendedType{Base: smartcontract.MapType, Key: smartcontract.StringType,
+			Value: &binding.ExtendedType{Base: smartcontract.IntegerType}}, "map[String]Integer"},
+		{"untyped array", smartcontract.ArrayType, &binding.ExtendedType{Base: smartcontract.ArrayType}, "Array"},
+	} {
+		t.Run("should convert "+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, cfg.convertType(tc.typ, tc.et))
+		})
+	}
+
+	t.Run("should fall back to the ABI type without converters", func(t *testing.T) {
+		plain := newTestCfg(manifest.NewManifest("test"))
+		assert.Equal(t, "Array", plain.convertType(smartcontract.ArrayType, point))
+		assert.Equal(t, "Map", plain.convertType(smartcontract.MapType, &binding.ExtendedType{Base: smartcontract.MapType,
+			Key: smartcontract.StringType, Value: &binding.ExtendedType{Base: smartcontract.IntegerType}}))
+	})
+}
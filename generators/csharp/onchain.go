@@ -4,7 +4,6 @@ import (
 	"cpm/generators"
 	"fmt"
 	"os"
-	"text/template"
 
 	"github.com/iancoleman/strcase"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
@@ -21,6 +20,20 @@ const csharpSrcTmpl = `
 {{- range $arg := .Arguments -}}, {{ .Name -}} {{ else }}, new object[0]{{end}});
 		} 
 {{- end -}}
+{{- define "EVENT" }}
+        public delegate void On{{ .Name }}Delegate({{ range $index, $arg := .Arguments }}{{ if ne $index 0 }}, {{ end }}{{ .Type }} {{ .Name }}{{ end }});
+
+        [DisplayName("{{ .NameABI }}")]
+        public static event On{{ .Name }}Delegate On{{ .Name }};
+{{- end -}}
+{{- define "STRUCT" }}
+        public class {{ .Name }} {
+        {{- range $f := .Fields }}
+            public {{ .Type }} {{ .Name }};
+        {{- end }}
+        }
+{{- end -}}
+using System.ComponentModel;
 using Neo;
 using Neo.Cryptography.ECC;
 using Neo.SmartContract;
@@ -33,6 +46,12 @@ namespace cpm {
 
         [InitialValue("{{.Hash}}", ContractParameterType.Hash160)]
         static readonly UInt160 ScriptHash;
+{{- range $s := .Structs}}
+{{ template "STRUCT" $s }}
+{{- end}}
+{{- range $e := .Events}}
+{{ template "EVENT" $e }}
+{{- end}}
 
         {{- range $m := .Methods}}
         {{ template "METHOD" $m -}}
@@ -50,12 +69,16 @@ func GenerateCsharpSDK(cfg *generators.GenerateCfg) error {
 
 	cfg.MethodNameConverter = strcase.ToCamel
 	cfg.ParamTypeConverter = scTypeToCsharp
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return value + "[]" }
+	cfg.MapTypeConverter = func(key, value string) string { return fmt.Sprintf("Map<%s, %s>", key, value) }
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
+	ctr.ConvertEventNames(cfg.MethodNameConverter)
 
-	tmp, err := template.New("generate").Parse(csharpSrcTmpl)
+	tmp, err := generators.ParseTemplate(cfg, "contract", csharpSrcTmpl, nil)
 	if err != nil {
 		return fmt.Errorf("failed to parse C# source template: %v", err)
 	}
@@ -70,12 +93,17 @@ func GenerateCsharpSDK(cfg *generators.GenerateCfg) error {
 		return fmt.Errorf("failed to get working directory: %v", err)
 	}
 
-	sdkLocation := wd + "/" + cfg.SdkDestination + generators.UpperFirst(cfg.Manifest.Name) + ".cs"
+	sdkLocation := wd + "/" + OutputPath(cfg.SdkDestination, cfg.Name())
 	log.Infof("Created SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, sdkLocation, cfg.ContractHash.StringLE())
 
 	return nil
 }
 
+// OutputPath returns the file the SDK of the contract is written to
+func OutputPath(destination, contractName string) string {
+	return destination + generators.UpperFirst(contractName) + ".cs"
+}
+
 func createCsharpPackage(cfg *generators.GenerateCfg) error {
 	dir := cfg.SdkDestination
 	err := os.MkdirAll(dir, 0755)
@@ -83,8 +111,8 @@ func createCsharpPackage(cfg *generators.GenerateCfg) error {
 		return fmt.Errorf("can't create directory %s: %w", dir, err)
 	}
 
-	filename := generators.UpperFirst(cfg.Manifest.Name)
-	f, err := os.Create(fmt.Sprintf(dir+"%s.cs", filename))
+	filename := generators.UpperFirst(cfg.Name())
+	f, err := os.Create(OutputPath(dir, cfg.Name()))
 	if err != nil {
 		f.Close()
 		return fmt.Errorf("can't create %s.cs file: %w", filename, err)
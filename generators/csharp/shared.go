@@ -0,0 +1,25 @@
+package csharp
+
+import "cpm/generators"
+
+func init() {
+	generators.Register(generator{})
+}
+
+type generator struct{}
+
+func (generator) Language() string    { return "csharp" }
+func (generator) DisplayName() string { return "C#" }
+func (generator) SDKTypes() []string  { return []string{generators.SDKOnChain} }
+
+func (generator) OutputPath(destination, contractName, sdkType string) string {
+	return OutputPath(destination, contractName)
+}
+
+func (generator) Templates(sdkType string) map[string]string {
+	return map[string]string{"contract": csharpSrcTmpl}
+}
+
+func (generator) Generate(cfg *generators.GenerateCfg, sdkType string) error {
+	return GenerateCsharpSDK(cfg)
+}
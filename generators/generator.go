@@ -0,0 +1,70 @@
+package generators
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Generator generates the SDKs of one language. Language packages register their generator in an init function, the
+// 'generate' command, the validation of cpm.yaml and the help text are built from the registered generators
+type Generator interface {
+	// Language is the name of the language in cpm.yaml and of its 'generate' subcommand, i.e. 'python'
+	Language() string
+	// DisplayName is the name of the language in help texts, i.e. 'Python'
+	DisplayName() string
+	// SDKTypes returns the supported SDK types, SDKOnChain and/or SDKOffChain
+	SDKTypes() []string
+	// OutputPath returns the file or directory the SDK of the contract is written to
+	OutputPath(destination, contractName, sdkType string) string
+	// Templates returns the built-in templates of the SDK type by name, or nil if the SDK can't be customized
+	Templates(sdkType string) map[string]string
+	// Generate writes the SDK of the type to cfg.SdkDestination
+	Generate(cfg *GenerateCfg, sdkType string) error
+}
+
+var registry = make(map[string]Generator)
+
+// Register makes the generator available for its language. It panics if the language is already registered
+func Register(g Generator) {
+	if _, ok := registry[g.Language()]; ok {
+		panic(fmt.Sprintf("generator for language '%s' is already registered", g.Language()))
+	}
+	registry[g.Language()] = g
+}
+
+// Unregister removes the generator of the language
+func Unregister(language string) {
+	delete(registry, language)
+}
+
+// Get returns the generator of the language or nil if there is none
+func Get(language string) Generator {
+	return registry[language]
+}
+
+// All returns the registered generators sorted by language
+func All() []Generator {
+	var all []Generator
+	for _, g := range registry {
+		all = append(all, g)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Language() < all[j].Language() })
+	return all
+}
+
+// Languages returns the sorted languages that support the SDK type
+func Languages(sdkType string) []string {
+	var languages []string
+	for _, g := range All() {
+		if Supports(g, sdkType) {
+			languages = append(languages, g.Language())
+		}
+	}
+	return languages
+}
+
+// Supports reports whether the generator supports the SDK type
+func Supports(g Generator, sdkType string) bool {
+	return slices.Contains(g.SDKTypes(), sdkType)
+}
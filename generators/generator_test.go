@@ -0,0 +1,82 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry(t *testing.T) {
+	t.Run("should look up registered generators by language", func(t *testing.T) {
+		require.NoError(t, RegisterPlugin("kotlin", "cpm-gen-kotlin"))
+		t.Cleanup(func() { Unregister("kotlin") })
+
+		g := Get("kotlin")
+		require.NotNil(t, g)
+		assert.Equal(t, "kotlin", g.Language())
+		assert.Nil(t, Get("rust"))
+	})
+
+	t.Run("should list generators sorted by language", func(t *testing.T) {
+		require.NoError(t, RegisterPlugin("kotlin", "cpm-gen-kotlin"))
+		require.NoError(t, RegisterPlugin("haskell", "cpm-gen-haskell"))
+		t.Cleanup(func() {
+			Unregister("kotlin")
+			Unregister("haskell")
+		})
+
+		var languages []string
+		for _, g := range All() {
+			languages = append(languages, g.Language())
+		}
+		assert.Equal(t, []string{"haskell", "kotlin"}, languages)
+	})
+
+	t.Run("should list languages that support the SDK type", func(t *testing.T) {
+		require.NoError(t, RegisterPlugin("kotlin", "cpm-gen-kotlin"))
+		Register(builtIn{})
+		t.Cleanup(func() {
+			Unregister("kotlin")
+			Unregister("go")
+		})
+
+		assert.Equal(t, []string{"go", "kotlin"}, Languages(SDKOffChain))
+		assert.Equal(t, []string{"kotlin"}, Languages(SDKOnChain))
+	})
+
+	t.Run("should replace a plugin registered before", func(t *testing.T) {
+		require.NoError(t, RegisterPlugin("kotlin", "cpm-gen-kotlin"))
+		require.NoError(t, RegisterPlugin("kotlin", "tools/cpm-gen-kotlin"))
+		t.Cleanup(func() { Unregister("kotlin") })
+
+		assert.Equal(t, "tools/cpm-gen-kotlin", Get("kotlin").(*Plugin).Path)
+	})
+
+	t.Run("should not replace built-in languages", func(t *testing.T) {
+		Register(builtIn{})
+		t.Cleanup(func() { Unregister("go") })
+
+		assert.ErrorContains(t, RegisterPlugin("go", "cpm-gen-go"), "language 'go' is built in")
+		assert.IsType(t, builtIn{}, Get("go"))
+	})
+
+	t.Run("should panic on duplicate registration", func(t *testing.T) {
+		Register(builtIn{})
+		t.Cleanup(func() { Unregister("go") })
+
+		assert.Panics(t, func() { Register(builtIn{}) })
+	})
+}
+
+// builtIn is a generator that stands in for the generators of the language packages, which import this package
+type builtIn struct{}
+
+func (builtIn) Language() string    { return "go" }
+func (builtIn) DisplayName() string { return "Go" }
+func (builtIn) SDKTypes() []string  { return []string{SDKOffChain} }
+func (builtIn) OutputPath(destination, contractName, _ string) string {
+	return destination + contractName
+}
+func (builtIn) Templates(string) map[string]string  { return nil }
+func (builtIn) Generate(*GenerateCfg, string) error { return nil }
@@ -5,12 +5,34 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"unicode"
 
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	generators.Register(generator{})
+}
+
+type generator struct{}
+
+func (generator) Language() string    { return "go" }
+func (generator) DisplayName() string { return "Golang" }
+func (generator) SDKTypes() []string  { return []string{generators.SDKOnChain, generators.SDKOffChain} }
+
+func (generator) OutputPath(destination, contractName, sdkType string) string {
+	return OutputPath(destination, contractName)
+}
+
+// Templates returns nil, Go SDKs are generated by neo-go
+func (generator) Templates(sdkType string) map[string]string { return nil }
+
+func (generator) Generate(cfg *generators.GenerateCfg, sdkType string) error {
+	return GenerateSDK(cfg, sdkType)
+}
+
 func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 	if sdkType == generators.SDKOnChain {
 		return generateSdk(cfg, goOnChainConfig(), goOnChainGenerate())
@@ -19,11 +41,31 @@ func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 	}
 }
 
+// OutputPath returns the file the SDK of the contract is written to
+func OutputPath(destination, contractName string) string {
+	return destination + strings.ToLower(contractName) + ".go"
+}
+
 type generateFunction func(binding.Config) error
 
 func generateSdk(cfg *generators.GenerateCfg, goconfig binding.Config, generate generateFunction) error {
 	goconfig.Manifest = cfg.Manifest
 	goconfig.Hash = cfg.ContractHash
+	if cfg.Bindings != nil {
+		goconfig.NamedTypes = cfg.Bindings.NamedTypes
+		goconfig.Types = cfg.Bindings.Types
+		goconfig.Overrides = cfg.Bindings.Overrides
+		goconfig.CallFlags = cfg.Bindings.CallFlags
+	}
+	if cfg.ContractName != "" {
+		// like neo-go derives the package name from the manifest name
+		goconfig.Package = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, cfg.ContractName)
+	}
 
 	dir := cfg.SdkDestination
 	err := os.MkdirAll(dir, 0755)
@@ -31,7 +73,7 @@ func generateSdk(cfg *generators.GenerateCfg, goconfig binding.Config, generate
 		return fmt.Errorf("can't create directory %s: %w", dir, err)
 	}
 
-	f, err := os.Create(dir + strings.ToLower(cfg.Manifest.Name) + ".go")
+	f, err := os.Create(OutputPath(dir, cfg.Name()))
 	if err != nil {
 		return fmt.Errorf("can't create output file: %w", err)
 	}
@@ -49,7 +91,7 @@ func generateSdk(cfg *generators.GenerateCfg, goconfig binding.Config, generate
 		return err
 	}
 
-	sdkLocation := wd + "/" + dir + strings.ToLower(cfg.Manifest.Name) + ".go"
+	sdkLocation := wd + "/" + OutputPath(dir, cfg.Name())
 	log.Infof("Created SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, sdkLocation, cfg.ContractHash.StringLE())
 	return nil
 }
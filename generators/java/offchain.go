@@ -8,6 +8,7 @@ import (
 
 	"github.com/iancoleman/strcase"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,7 +20,7 @@ const javaOffChainSrcTmpl = `
 		return smartContract.invokeFunction("{{ .NameABI }}"{{if not .Arguments -}} ); {{- else}},
 			{{- $length := len .Arguments -}}
 			{{- range $index, $arg := .Arguments}}
-			{{ Neow3jWrapParameter $arg.TypeABI }}({{ .Name }}){{ if lt $index (Dec $length) }},{{ end }}
+			{{ Neow3jParameter .Name .TypeABI .ExtendedType }}{{ if lt $index (Dec $length) }},{{ end }}
 			{{- end}}
 		);{{- end}}
 	}
@@ -43,7 +44,7 @@ const javaOffChainSrcTmpl = `
 				Arrays.asList(
 					{{- end -}}
 					{{- range $index, $arg := .Arguments }}
-					{{ Neow3jWrapParameter $arg.TypeABI }}({{ .Name }}){{ if lt $index (Dec $length) }},{{ end }}
+					{{ Neow3jParameter .Name .TypeABI .ExtendedType }}{{ if lt $index (Dec $length) }},{{ end }}
 					{{- end }}
 				),
 				{{ end -}}
@@ -56,17 +57,83 @@ const javaOffChainSrcTmpl = `
 			throw new RuntimeException(e);
 		}
 		{{-  if ne .ReturnTypeABI "Void" }}
-		return {{ Neow3jReturnTestInvoke .ReturnTypeABI }};
+		return {{ Neow3jResult .ReturnTypeABI .ReturnExtendedType }};
 		{{-  end }}
 	}
 {{- end -}}
+{{- define "STRUCT" }}
+	public static class {{ .Name }} {
+		{{- range $f := .Fields }}
+		public {{ .Type }} {{ .Name }};
+		{{- end }}
+
+		public {{ .Name }}({{ range $index, $f := .Fields }}{{ if ne $index 0 }}, {{ end }}{{ .Type }} {{ .Name }}{{ end }}) {
+			{{- range $f := .Fields }}
+			this.{{ .Name }} = {{ .Name }};
+			{{- end }}
+		}
+
+		public static {{ .Name }} fromStackItem(StackItem item) {
+			List<StackItem> fields = item.getList();
+			return new {{ .Name }}(
+				{{- $length := len .Fields }}
+				{{- range $index, $f := .Fields }}
+				{{ Neow3jDecode (printf "fields.get(%d)" $index) .TypeABI .ExtendedType }}{{ if lt $index (Dec $length) }},{{ end }}
+				{{- end }}
+			);
+		}
+
+		public ContractParameter toContractParameter() {
+			return ContractParameter.array(Arrays.asList(
+				{{- $length := len .Fields }}
+				{{- range $index, $f := .Fields }}
+				{{ Neow3jParameter .Name .TypeABI .ExtendedType }}{{ if lt $index (Dec $length) }},{{ end }}
+				{{- end }}
+			));
+		}
+	}
+{{- end -}}
+{{- define "EVENT" }}
+	public static class {{ ToCamel .NameABI }}Event {
+		{{- range $arg := .Arguments }}
+		public {{ .Type }} {{ .Name }};
+		{{- end }}
+
+		public {{ ToCamel .NameABI }}Event({{ range $index, $arg := .Arguments }}{{ if ne $index 0 }}, {{ end }}{{ .Type }} {{ .Name }}{{ end }}) {
+			{{- range $arg := .Arguments }}
+			this.{{ .Name }} = {{ .Name }};
+			{{- end }}
+		}
+
+		public static {{ ToCamel .NameABI }}Event fromNotification(Notification notification) {
+			List<StackItem> state = notification.getState().getList();
+			return new {{ ToCamel .NameABI }}Event(
+				{{- $length := len .Arguments }}
+				{{- range $index, $arg := .Arguments }}
+				{{ Neow3jDecode (printf "state.get(%d)" $index) .TypeABI .ExtendedType }}{{ if lt $index (Dec $length) }},{{ end }}
+				{{- end }}
+			);
+		}
+	}
+{{- end -}}
+{{- define "EVENTLOG" }}
+	public List<{{ ToCamel .NameABI }}Event> get{{ ToCamel .NameABI }}Events(NeoApplicationLog applicationLog) {
+		return applicationLog.getExecutions().stream()
+			.flatMap(execution -> execution.getNotifications().stream())
+			.filter(notification -> notification.getContract().equals(scriptHash) && notification.getEventName().equals("{{ .NameABI }}"))
+			.map({{ ToCamel .NameABI }}Event::fromNotification)
+			.collect(Collectors.toList());
+	}
+{{- end -}}
 package <REPLACE ME>;
 
 import io.neow3j.contract.SmartContract;
 import io.neow3j.crypto.ECKeyPair;
 import io.neow3j.protocol.Neow3j;
 import io.neow3j.protocol.Neow3jConfig;
+import io.neow3j.protocol.core.response.NeoApplicationLog;
 import io.neow3j.protocol.core.response.NeoInvokeFunction;
+import io.neow3j.protocol.core.response.Notification;
 import io.neow3j.protocol.core.stackitem.StackItem;
 import io.neow3j.protocol.http.HttpService;
 import io.neow3j.transaction.AccountSigner;
@@ -82,6 +149,7 @@ import java.util.Arrays;
 import java.util.Collections;
 import java.util.List;
 import java.util.Map;
+import java.util.stream.Collectors;
 
 public class {{ .ContractName }} {
 	Neow3j neow3j;
@@ -101,6 +169,13 @@ public class {{ .ContractName }} {
         setScriptHash(new Hash160("{{ .Hash }}"));
         setSmartContract(new SmartContract(scriptHash, neow3j));
     }
+{{- range $s := .Structs}}
+{{ template "STRUCT" $s }}
+{{- end}}
+{{- range $e := .Events}}
+{{ template "EVENT" $e }}
+{{ template "EVENTLOG" $e }}
+{{- end}}
 {{  range $m := .Methods}}
 {{- if .Safe }}
 {{- template "TESTINVOKEMETHOD" $m -}}
@@ -112,6 +187,25 @@ public class {{ .ContractName }} {
 }
 `
 
+// offChainFuncMap are the functions of the off-chain template in addition to generators.FuncMap
+//
+//   - Neow3jWrapParameter returns the ContractParameter factory for an ABI type, i.e. 'ContractParameter.hash160'
+//   - Neow3jReturnType replaces the unbound List and Map types by List<StackItem> and Map<StackItem, StackItem>
+//   - Neow3jReturnTestInvoke returns the expression reading a result of the ABI type from a test invocation response
+//   - Neow3jParameter returns the ContractParameter of a variable of the ABI or extended type
+//   - Neow3jDecode returns the expression reading a value of the ABI or extended type from a StackItem
+//   - Neow3jResult returns the expression reading a result of the ABI or extended type from a test invocation response
+//   - Dec decrements a number, i.e. to test for the last element of a range
+var offChainFuncMap = template.FuncMap{
+	"Neow3jWrapParameter":    neow3jWrapParameterTypes,
+	"Neow3jReturnType":       changeListMapReturnTypeJava,
+	"Neow3jReturnTestInvoke": offchainJavaReturn,
+	"Neow3jParameter":        neow3jParameter,
+	"Neow3jDecode":           neow3jDecode,
+	"Neow3jResult":           neow3jResult,
+	"Dec":                    decreaseNumber,
+}
+
 func generateOffchainSDK(cfg *generators.GenerateCfg) error {
 	err := createJavaPackage(cfg)
 	defer cfg.ContractOutput.Close()
@@ -121,21 +215,16 @@ func generateOffchainSDK(cfg *generators.GenerateCfg) error {
 
 	cfg.MethodNameConverter = strcase.ToLowerCamel
 	cfg.ParamTypeConverter = offchainScParameterTypeToJava
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return fmt.Sprintf("List<%s>", box(value)) }
+	cfg.MapTypeConverter = func(key, value string) string { return fmt.Sprintf("Map<%s, %s>", box(key), box(value)) }
 	cfg.SupportMethodOverload = true
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
 
-	funcMap := template.FuncMap{
-		"Neow3jWrapParameter":    neow3jWrapParameterTypes,
-		"Neow3jReturnType":       changeListMapReturnTypeJava,
-		"Neow3jReturnTestInvoke": offchainJavaReturn,
-		"UpperFirst":             generators.UpperFirst,
-		"Dec":                    decreaseNumber,
-	}
-
-	tmp, err := template.New("generate").Funcs(funcMap).Parse(javaOffChainSrcTmpl)
+	tmp, err := generators.ParseTemplate(cfg, "contract", javaOffChainSrcTmpl, offChainFuncMap)
 	if err != nil {
 		return fmt.Errorf("failed to parse Java source template: %v", err)
 	}
@@ -162,8 +251,8 @@ func createOffchainJavaPackage(cfg *generators.GenerateCfg) error {
 		return fmt.Errorf("can't create directory %s: %w", dir, err)
 	}
 
-	filename := generators.UpperFirst(cfg.Manifest.Name)
-	f, err := os.Create(fmt.Sprintf(dir+"%s.java", filename))
+	filename := generators.UpperFirst(cfg.Name())
+	f, err := os.Create(OutputPath(dir, cfg.Name()))
 	if err != nil {
 		f.Close()
 		return fmt.Errorf("can't create %s.java file: %w", filename, err)
@@ -216,31 +305,87 @@ func changeListMapReturnTypeJava(typ string) string {
 }
 
 func offchainJavaReturn(typ string) string {
-	switch typ {
-	case "Any":
-		return "response.getInvocationResult().getFirstStackItem().getValue()"
-	case "InteropInterface":
+	return neow3jResult(typ, nil)
+}
+
+func neow3jResult(typ string, et *binding.ExtendedType) string {
+	if typ == "InteropInterface" {
 		return "response"
-	case "Boolean":
-		return "response.getInvocationResult().getFirstStackItem().getBoolean()"
-	case "Integer":
-		return "response.getInvocationResult().getFirstStackItem().getInteger()"
-	case "ByteArray":
-		return "response.getInvocationResult().getFirstStackItem().getByteArray()"
-	case "String":
-		return "response.getInvocationResult().getFirstStackItem().getString()"
-	case "Hash160":
-		return "Hash160.fromAddress(response.getInvocationResult().getFirstStackItem().getAddress())"
-	case "Hash256":
-		return "new Hash256(ArrayUtils.reverseArray(response.getInvocationResult().getFirstStackItem().getByteArray()))"
-	case "PublicKey":
-		return "new ECKeyPair.ECPublicKey(response.getInvocationResult().getFirstStackItem().getHexString())"
-	case "Array":
-		return "response.getInvocationResult().getFirstStackItem().getList()"
-	case "Map":
-		return "response.getInvocationResult().getFirstStackItem().getMap()"
+	}
+	return neow3jDecode("response.getInvocationResult().getFirstStackItem()", typ, et)
+}
+
+func neow3jDecode(item, typ string, et *binding.ExtendedType) string {
+	return stackItemToJava(item, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// stackItemToJava returns the expression reading a value of the type from the StackItem expression. depth numbers the
+// variables of nested lambdas, Java does not allow them to shadow each other
+func stackItemToJava(item string, et binding.ExtendedType, depth int) string {
+	switch et.Base {
+	case smartcontract.AnyType:
+		return item + ".getValue()"
+	case smartcontract.InteropInterfaceType:
+		return item
+	case smartcontract.BoolType:
+		return item + ".getBoolean()"
+	case smartcontract.IntegerType:
+		return item + ".getInteger()"
+	case smartcontract.ByteArrayType, smartcontract.SignatureType:
+		return item + ".getByteArray()"
+	case smartcontract.StringType:
+		return item + ".getString()"
+	case smartcontract.Hash160Type:
+		return "Hash160.fromAddress(" + item + ".getAddress())"
+	case smartcontract.Hash256Type:
+		return "new Hash256(ArrayUtils.reverseArray(" + item + ".getByteArray()))"
+	case smartcontract.PublicKeyType:
+		return "new ECKeyPair.ECPublicKey(" + item + ".getHexString())"
+	case smartcontract.ArrayType:
+		if et.Name != "" {
+			return generators.StructName(et.Name) + ".fromStackItem(" + item + ")"
+		}
+		if et.Value != nil {
+			v := fmt.Sprintf("item%d", depth)
+			return fmt.Sprintf("%s.getList().stream().map(%s -> %s).collect(Collectors.toList())",
+				item, v, stackItemToJava(v, *et.Value, depth+1))
+		}
+		return item + ".getList()"
+	case smartcontract.MapType:
+		if et.Value != nil {
+			e := fmt.Sprintf("entry%d", depth)
+			return fmt.Sprintf("%s.getMap().entrySet().stream().collect(Collectors.toMap(%s -> %s, %s -> %s))",
+				item, e, stackItemToJava(e+".getKey()", binding.ExtendedType{Base: et.Key}, depth+1),
+				e, stackItemToJava(e+".getValue()", *et.Value, depth+1))
+		}
+		return item + ".getMap()"
 	default:
-		panic(fmt.Sprintf("unknown type: %T %s", typ, typ))
+		panic(fmt.Sprintf("unknown type: %T %s", et.Base, et.Base))
+	}
+}
+
+func neow3jParameter(name, typ string, et *binding.ExtendedType) string {
+	return javaToContractParameter(name, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// javaToContractParameter returns the expression converting the variable of the type to a ContractParameter
+func javaToContractParameter(name string, et binding.ExtendedType, depth int) string {
+	switch {
+	case et.Base == smartcontract.ArrayType && et.Name != "":
+		return name + ".toContractParameter()"
+	case et.Base == smartcontract.ArrayType && et.Value != nil:
+		v := fmt.Sprintf("item%d", depth)
+		return fmt.Sprintf("ContractParameter.array(%s.stream().map(%s -> %s).collect(Collectors.toList()))",
+			name, v, javaToContractParameter(v, *et.Value, depth+1))
+	case et.Base == smartcontract.MapType && et.Value != nil:
+		e := fmt.Sprintf("entry%d", depth)
+		return fmt.Sprintf("ContractParameter.map(%s.entrySet().stream().collect(Collectors.toMap(%s -> %s, %s -> %s)))",
+			name, e, javaToContractParameter(e+".getKey()", binding.ExtendedType{Base: et.Key}, depth+1),
+			e, javaToContractParameter(e+".getValue()", *et.Value, depth+1))
+	case et.Base == smartcontract.SignatureType:
+		return "ContractParameter.byteArray(" + name + ")"
+	default:
+		return neow3jWrapParameterTypes(et.Base.String()) + "(" + name + ")"
 	}
 }
 
@@ -12,6 +12,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// maxEventArguments is the number of arguments of the largest neow3j event type, Event16Args
+const maxEventArguments = 16
+
 const javaSrcTmpl = `
 {{- define "METHOD" }}
     public native {{.ReturnType }} {{.NameABI}}({{range $index, $arg := .Arguments -}}
@@ -19,10 +22,31 @@ const javaSrcTmpl = `
           {{- .Type}} {{.Name}}
        {{- end}});
 {{- end -}}
+{{- define "EVENT" }}
+    @DisplayName("{{ .NameABI }}")
+    public static Event{{ len .Arguments }}Arg{{ if ne (len .Arguments) 1 }}s{{ end }}{{ if .Arguments }}<{{ range $index, $arg := .Arguments }}{{ if ne $index 0 }}, {{ end }}{{ Box .Type }}{{ end }}>{{ end }} on{{ UpperFirst .Name }};
+{{- end -}}
+{{- define "STRUCT" }}
+    @Struct
+    public static class {{ .Name }} {
+    {{- range $f := .Fields }}
+        public {{ .Type }} {{ .Name }};
+    {{- end }}
+    }
+{{- end -}}
 package <REPLACE_ME>;
 
 import io.neow3j.devpack.*;
+{{- if .Events }}
+import io.neow3j.devpack.annotations.DisplayName;
+{{- end }}
+{{- if .Structs }}
+import io.neow3j.devpack.annotations.Struct;
+{{- end }}
 import io.neow3j.devpack.contracts.ContractInterface;
+{{- if .Events }}
+import io.neow3j.devpack.events.*;
+{{- end }}
 
 
 public class {{ .ContractName }} extends ContractInterface {
@@ -32,6 +56,12 @@ public class {{ .ContractName }} extends ContractInterface {
     public {{ .ContractName }}() {
        super(scriptHash);
     }
+{{- range $s := .Structs}}
+{{ template "STRUCT" $s }}
+{{- end}}
+{{- range $e := .Events}}
+{{ template "EVENT" $e }}
+{{- end}}
 
 {{- range $m := .Methods}}
 {{ template "METHOD" $m -}}
@@ -39,6 +69,13 @@ public class {{ .ContractName }} extends ContractInterface {
 }
 `
 
+// onChainFuncMap are the functions of the on-chain template in addition to generators.FuncMap
+//
+//   - Box returns the class of a primitive type, i.e. 'Integer' for 'int', for the type arguments of events
+var onChainFuncMap = template.FuncMap{
+	"Box": box,
+}
+
 func generateOnchainSDK(cfg *generators.GenerateCfg) error {
 	err := createJavaPackage(cfg)
 	defer cfg.ContractOutput.Close()
@@ -48,13 +85,27 @@ func generateOnchainSDK(cfg *generators.GenerateCfg) error {
 
 	cfg.MethodNameConverter = strcase.ToLowerCamel
 	cfg.ParamTypeConverter = scTypeToJava
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return fmt.Sprintf("List<%s>", box(value)) }
+	cfg.MapTypeConverter = func(key, value string) string { return fmt.Sprintf("Map<%s, %s>", box(key), box(value)) }
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
 	ctr.Hash = strings.TrimPrefix(ctr.Hash, "0x")
+	ctr.ConvertEventNames(cfg.MethodNameConverter)
+	events := ctr.Events[:0]
+	for _, e := range ctr.Events {
+		if len(e.Arguments) > maxEventArguments {
+			log.Warnf("Skipping event '%s' of contract '%s', neow3j events can't have more than %d arguments", e.NameABI,
+				cfg.Manifest.Name, maxEventArguments)
+			continue
+		}
+		events = append(events, e)
+	}
+	ctr.Events = events
 
-	tmp, err := template.New("generate").Parse(javaSrcTmpl)
+	tmp, err := generators.ParseTemplate(cfg, "contract", javaSrcTmpl, onChainFuncMap)
 	if err != nil {
 		return fmt.Errorf("failed to parse Java source template: %v", err)
 	}
@@ -81,8 +132,8 @@ func createJavaPackage(cfg *generators.GenerateCfg) error {
 		return fmt.Errorf("can't create directory %s: %w", dir, err)
 	}
 
-	filename := generators.UpperFirst(cfg.Manifest.Name)
-	f, err := os.Create(fmt.Sprintf(dir+"%s.java", filename))
+	filename := generators.UpperFirst(cfg.Name())
+	f, err := os.Create(OutputPath(dir, cfg.Name()))
 	if err != nil {
 		f.Close()
 		return fmt.Errorf("can't create %s.java file: %w", filename, err)
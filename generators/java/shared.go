@@ -2,6 +2,31 @@ package java
 
 import "cpm/generators"
 
+func init() {
+	generators.Register(generator{})
+}
+
+type generator struct{}
+
+func (generator) Language() string    { return "java" }
+func (generator) DisplayName() string { return "Java" }
+func (generator) SDKTypes() []string  { return []string{generators.SDKOnChain, generators.SDKOffChain} }
+
+func (generator) OutputPath(destination, contractName, sdkType string) string {
+	return OutputPath(destination, contractName)
+}
+
+func (generator) Templates(sdkType string) map[string]string {
+	if sdkType == generators.SDKOnChain {
+		return map[string]string{"contract": javaSrcTmpl}
+	}
+	return map[string]string{"contract": javaOffChainSrcTmpl}
+}
+
+func (generator) Generate(cfg *generators.GenerateCfg, sdkType string) error {
+	return GenerateSDK(cfg, sdkType)
+}
+
 func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 	if sdkType == generators.SDKOnChain {
 		return generateOnchainSDK(cfg)
@@ -9,3 +34,20 @@ func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 		return generateOffchainSDK(cfg)
 	}
 }
+
+// OutputPath returns the file the SDK of the contract is written to
+func OutputPath(destination, contractName string) string {
+	return destination + generators.UpperFirst(contractName) + ".java"
+}
+
+// box returns the class of a primitive type, which generic types like List<Integer> need
+func box(typ string) string {
+	switch typ {
+	case "int":
+		return "Integer"
+	case "boolean":
+		return "Boolean"
+	default:
+		return typ
+	}
+}
@@ -0,0 +1,208 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	log "github.com/sirupsen/logrus"
+)
+
+// PluginPrefix is the prefix of generator plugin executables, which are named 'cpm-gen-<language>'
+const PluginPrefix = "cpm-gen-"
+
+// PluginTimeout is the time a generator plugin gets to generate an SDK before it is killed
+const PluginTimeout = 2 * time.Minute
+
+type (
+	// Plugin is a generator implemented by an external executable. For every SDK the executable is started, reads a
+	// PluginRequest as JSON from stdin and writes a PluginResponse as JSON to stdout. The files of the response are
+	// written by cpm to a directory named after the contract in the SDK destination
+	Plugin struct {
+		language string
+		// Path of the executable
+		Path string
+	}
+
+	PluginRequest struct {
+		// Contract is the contract with ABI types, i.e. 'Hash160', and unconverted method names
+		Contract     ContractTmpl    `json:"contract"`
+		Manifest     json.RawMessage `json:"manifest"`
+		ContractHash string          `json:"contractHash"`
+		SdkType      string          `json:"sdkType"`
+		Destination  string          `json:"destination"`
+	}
+
+	PluginResponse struct {
+		// Files are written relative to the SDK directory of the contract
+		Files []PluginFile `json:"files"`
+		// Error is set if the plugin could not generate the SDK, i.e. because it does not support the SDK type
+		Error string `json:"error,omitempty"`
+	}
+
+	PluginFile struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+)
+
+// NewPlugin returns the executable as generator of the language without registering it. Languages built into cpm
+// can't be provided by a plugin
+func NewPlugin(language, path string) (*Plugin, error) {
+	if g := Get(language); g != nil {
+		if _, ok := g.(*Plugin); !ok {
+			return nil, fmt.Errorf("language '%s' is built in and can't be provided by generator plugin %s", language, path)
+		}
+	}
+	return &Plugin{language: language, Path: path}, nil
+}
+
+// RegisterPlugin registers the executable as generator of the language. It replaces a plugin registered for the
+// language before, but languages built into cpm can't be replaced
+func RegisterPlugin(language, path string) error {
+	p, err := NewPlugin(language, path)
+	if err != nil {
+		return err
+	}
+	Unregister(language)
+	Register(p)
+	return nil
+}
+
+// RegisterPathPlugins registers all 'cpm-gen-<language>' executables found in $PATH. Executables of built-in languages
+// are ignored
+func RegisterPathPlugins() {
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			language, ok := strings.CutPrefix(e.Name(), PluginPrefix)
+			language = strings.TrimSuffix(language, ".exe")
+			if !ok || language == "" || seen[language] || !isExecutable(e) {
+				continue
+			}
+			// the first executable in $PATH wins, like it does for a shell
+			seen[language] = true
+			path := filepath.Join(dir, e.Name())
+			if err := RegisterPlugin(language, path); err != nil {
+				log.Debugf("Ignoring generator plugin: %v", err)
+				continue
+			}
+			log.Debugf("Found generator plugin %s for language '%s'", path, language)
+		}
+	}
+}
+
+// isExecutable reports whether the directory entry is a file that can be executed. Windows has no executable bit, so
+// every file counts there
+func isExecutable(e os.DirEntry) bool {
+	info, err := e.Info()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0111 != 0
+}
+
+func (p *Plugin) Language() string    { return p.language }
+func (p *Plugin) DisplayName() string { return p.language }
+
+// SDKTypes returns both SDK types, a plugin that does not support a type must respond with an error
+func (p *Plugin) SDKTypes() []string { return []string{SDKOnChain, SDKOffChain} }
+
+func (p *Plugin) OutputPath(destination, contractName, sdkType string) string {
+	return destination + cleanContractName(contractName)
+}
+
+// Templates returns nil, plugins have their own templates
+func (p *Plugin) Templates(sdkType string) map[string]string { return nil }
+
+func (p *Plugin) Generate(cfg *GenerateCfg, sdkType string) error {
+	if name := cleanContractName(cfg.Name()); !filepath.IsLocal(name) {
+		return fmt.Errorf("contract name '%s' can't be used as SDK directory, set an sdk-name", cfg.Name())
+	}
+
+	tmplCfg := *cfg
+	tmplCfg.ParamTypeConverter = smartcontract.ParamType.String
+	tmplCfg.MethodNameConverter = func(s string) string { return s }
+	ctr, err := TemplateFromManifest(&tmplCfg)
+	if err != nil {
+		return fmt.Errorf("failed to parse manifest into contract template: %w", err)
+	}
+	manifest, err := json.Marshal(cfg.Manifest)
+	if err != nil {
+		return err
+	}
+	request, err := json.Marshal(PluginRequest{
+		Contract:     ctr,
+		Manifest:     manifest,
+		ContractHash: "0x" + cfg.ContractHash.StringLE(),
+		SdkType:      sdkType,
+		Destination:  cfg.SdkDestination,
+	})
+	if err != nil {
+		return err
+	}
+
+	parent := cfg.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, PluginTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if parent.Err() != nil {
+			return fmt.Errorf("generator plugin %s was stopped: %w", p.Path, parent.Err())
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("generator plugin %s did not finish within %s", p.Path, PluginTimeout)
+		}
+		return fmt.Errorf("generator plugin %s failed: %w: %s", p.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 {
+		log.Debugf("Generator plugin %s: %s", p.Path, strings.TrimSpace(stderr.String()))
+	}
+
+	var response PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return fmt.Errorf("invalid response of generator plugin %s: %w", p.Path, err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("generator plugin %s failed: %s", p.Path, response.Error)
+	}
+	if len(response.Files) == 0 {
+		return fmt.Errorf("generator plugin %s returned no files", p.Path)
+	}
+
+	dir := p.OutputPath(cfg.SdkDestination, cfg.Name(), sdkType)
+	for _, f := range response.Files {
+		if !filepath.IsLocal(f.Path) {
+			return fmt.Errorf("generator plugin %s returned file %s outside of the SDK directory", p.Path, f.Path)
+		}
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("can't create directory %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return fmt.Errorf("can't write %s: %w", path, err)
+		}
+	}
+
+	log.Infof("Created SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, dir, cfg.ContractHash.StringLE())
+	return nil
+}
@@ -0,0 +1,31 @@
+package generators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Plugin(t *testing.T) {
+	p := &Plugin{language: "kotlin", Path: "cpm-gen-kotlin"}
+
+	t.Run("should write the SDK to a directory named after the contract", func(t *testing.T) {
+		assert.Equal(t, "out/SampleContract", p.OutputPath("out/", "Sample Contract", SDKOffChain))
+		assert.Equal(t, "out/Escape", p.OutputPath("out/", "../escape", SDKOffChain))
+	})
+
+	t.Run("should refuse contract names without a directory name", func(t *testing.T) {
+		err := p.Generate(&GenerateCfg{Manifest: manifest.NewManifest("../..")}, SDKOffChain)
+		assert.ErrorContains(t, err, "can't be used as SDK directory")
+	})
+
+	t.Run("should stop the plugin when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sleep := &Plugin{language: "kotlin", Path: "sleep"}
+		err := sleep.Generate(&GenerateCfg{Manifest: manifest.NewManifest("test"), Context: ctx}, SDKOffChain)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
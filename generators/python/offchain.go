@@ -4,11 +4,10 @@ import (
 	"cpm/generators"
 	"fmt"
 	"os"
-	"strings"
 	"text/template"
 
-	"github.com/iancoleman/strcase"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,11 +16,6 @@ const pythonOffChainSrcTmpl = `
 	def {{.Name}}(self{{range $index, $arg := .Arguments -}}
 		, {{.Name}}: {{.Type}}
 		{{- end}}) -> ContractMethodResult[{{if eq .ReturnTypeABI "InteropInterface" }}list{{ else }}{{ .ReturnType }}{{ end }}]:
-		{{- range $index, $arg := .Arguments}}
-		{{- if eq $arg.TypeABI "Hash160" }}
-		{{.Name}} = _check_address_and_convert({{.Name}})
-		{{- end}}
-		{{- end}}
 		script = (
 			vm.ScriptBuilder()
 			{{if .Arguments -}}
@@ -31,7 +25,7 @@ const pythonOffChainSrcTmpl = `
 			.emit_contract_call_with_args
 			{{- end -}}
 			(self.hash, "{{ .NameABI }}", [{{range $index, $arg := .Arguments}}
-				{{- if ne $index 0}}, {{end}}{{- .Name}}
+				{{- if ne $index 0}}, {{end}}{{ MambaParameter .Name .TypeABI .ExtendedType }}
 				{{- end}}])
 			{{- else -}}
 			{{if eq .ReturnTypeABI "InteropInterface" -}}
@@ -43,25 +37,99 @@ const pythonOffChainSrcTmpl = `
 			{{- end}}
 			.to_array()
 		)
-		return ContractMethodResult(script, {{ MambaUnwrap .ReturnTypeABI }})
+		return ContractMethodResult(script, {{ MambaResult .ReturnTypeABI .ReturnExtendedType }})
 {{- end -}}
+{{- define "STRUCT" }}
+@dataclass
+class {{ .Name }}:
+	{{- range $f := .Fields }}
+	{{ .Name }}: {{ .Type }}
+	{{- end }}
+
+	@classmethod
+	def from_stack_item(cls, item: noderpc.StackItem) -> "{{ .Name }}":
+		fields = item.as_list()
+		return cls(
+			{{- range $index, $f := .Fields }}
+			{{ MambaDecode (printf "fields[%d]" $index) .TypeABI .ExtendedType }},
+			{{- end }}
+		)
+
+	def to_list(self) -> list:
+		return [
+			{{- range $index, $f := .Fields }}
+			{{ MambaParameter (printf "self.%s" .Name) .TypeABI .ExtendedType }},
+			{{- end }}
+		]
+{{- end -}}
+{{- define "EVENT" }}
+@dataclass
+class {{ ToCamel .NameABI }}Event:
+	{{- range $arg := .Arguments }}
+	{{ .Name }}: {{ .Type }}
+	{{- end }}
+
+	@classmethod
+	def from_notification(cls, notification: noderpc.Notification) -> "{{ ToCamel .NameABI }}Event":
+		state = notification.state.as_list()
+		return cls(
+			{{- range $index, $arg := .Arguments }}
+			{{ MambaDecode (printf "state[%d]" $index) .TypeABI .ExtendedType }},
+			{{- end }}
+		)
+{{- end -}}
+{{- define "EVENTLOG" }}
+	def {{ .Name }}_events(self, log: noderpc.TransactionApplicationLog) -> list[{{ ToCamel .NameABI }}Event]:
+		return [
+			{{ ToCamel .NameABI }}Event.from_notification(notification)
+			for notification in log.execution.notifications
+			if notification.contract == self.hash and notification.event_name == "{{ .NameABI }}"
+		]
+{{- end -}}
+{{- if or .Structs .Events -}}
+from dataclasses import dataclass
+{{ end -}}
 from neo3 import vm
 from neo3.api import noderpc
 from neo3.api.helpers import unwrap
 from neo3.api.wrappers import GenericContract, ContractMethodResult, _check_address_and_convert
 from neo3.core import types, cryptography, serialization
 from neo3.wallet.types import NeoAddress
+{{- range $s := .Structs}}
+
+{{ template "STRUCT" $s }}
+{{- end}}
+{{- range $e := .Events}}
+
+{{ template "EVENT" $e }}
+{{- end}}
 
 
 class {{ .ContractName }}(GenericContract):
 	def __init__(self):
 		super().__init__(types.UInt160.from_string("{{ .Hash }}"))
+{{- range $e := .Events}}
+{{ template "EVENTLOG" $e }}
+{{- end}}
 
 {{- range $m := .Methods}}
 {{ template "METHOD" $m -}}
 {{end}}
 `
 
+// offChainFuncMap are the functions of the off-chain template in addition to generators.FuncMap
+//
+//   - MambaUnwrap returns the neo-mamba unwrap function for an ABI type, i.e. 'unwrap.as_int'
+//   - MambaParameter returns the script argument of a variable of the ABI or extended type
+//   - MambaDecode returns the expression reading a value of the ABI or extended type from a noderpc.StackItem
+//   - MambaResult returns the function reading a result of the ABI or extended type from an invocation result
+var offChainFuncMap = template.FuncMap{
+	"MambaUnwrap":    mambaUnwrapTypes,
+	"MambaParameter": mambaParameter,
+	"MambaDecode":    mambaDecode,
+	"MambaResult":    mambaResult,
+}
+
 func generateOffchainSDK(cfg *generators.GenerateCfg) error {
 	err := createOffChainPythonPackage(cfg)
 	defer cfg.ContractOutput.Close()
@@ -69,18 +137,25 @@ func generateOffchainSDK(cfg *generators.GenerateCfg) error {
 		return err
 	}
 
-	cfg.MethodNameConverter = strcase.ToSnake
+	cfg.MethodNameConverter = pythonName
 	cfg.ParamTypeConverter = scTypeToNeoMamba
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return fmt.Sprintf("list[%s]", value) }
+	cfg.MapTypeConverter = func(key, value string) string { return fmt.Sprintf("dict[%s, %s]", key, value) }
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
-
-	funcMap := template.FuncMap{
-		"MambaUnwrap": mambaUnwrapTypes,
+	escapeArguments(&ctr)
+	ctr.ConvertEventNames(cfg.MethodNameConverter)
+	// the arguments of events are fields of the event classes
+	for _, e := range ctr.Events {
+		for i := range e.Arguments {
+			e.Arguments[i].Name = pythonIdentifier(e.Arguments[i].Name)
+		}
 	}
 
-	tmp, err := template.New("generate").Funcs(funcMap).Parse(pythonOffChainSrcTmpl)
+	tmp, err := generators.ParseTemplate(cfg, "contract", pythonOffChainSrcTmpl, offChainFuncMap)
 	if err != nil {
 		return fmt.Errorf("failed to parse Python off chain source template: %v", err)
 	}
@@ -94,14 +169,14 @@ func generateOffchainSDK(cfg *generators.GenerateCfg) error {
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %v", err)
 	}
-	sdkLocation := wd + "/" + cfg.SdkDestination + generators.UpperFirst(cfg.Manifest.Name)
+	sdkLocation := wd + "/" + cfg.SdkDestination + generators.UpperFirst(cfg.Name())
 	log.Infof("Created off chain SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, sdkLocation, cfg.ContractHash.StringLE())
 
 	return nil
 }
 
 func createOffChainPythonPackage(cfg *generators.GenerateCfg) error {
-	sdkDir := cfg.SdkDestination + strings.ReplaceAll(strings.ToLower(cfg.Manifest.Name), " ", "_")
+	sdkDir := OutputPath(cfg.SdkDestination, cfg.Name(), generators.SDKOffChain)
 	err := os.MkdirAll(sdkDir, 0755)
 	if err != nil {
 		return fmt.Errorf("can't create off-chain directory %s: %w", sdkDir, err)
@@ -183,3 +258,83 @@ func mambaUnwrapTypes(typ string) string {
 		panic(fmt.Sprintf("unknown type: %T %s", typ, typ))
 	}
 }
+
+func mambaResult(typ string, et *binding.ExtendedType) string {
+	if !generators.Structured(et) {
+		return mambaUnwrapTypes(typ)
+	}
+	return "lambda res: " + stackItemToPython("unwrap.item(res)", *et, 0)
+}
+
+func mambaDecode(item, typ string, et *binding.ExtendedType) string {
+	return stackItemToPython(item, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// stackItemToPython returns the expression reading a value of the type from the noderpc.StackItem expression. depth
+// numbers the variables of nested comprehensions
+func stackItemToPython(item string, et binding.ExtendedType, depth int) string {
+	switch et.Base {
+	case smartcontract.AnyType, smartcontract.InteropInterfaceType:
+		return item
+	case smartcontract.BoolType:
+		return item + ".as_bool()"
+	case smartcontract.IntegerType:
+		return item + ".as_int()"
+	case smartcontract.ByteArrayType, smartcontract.SignatureType:
+		return item + ".as_bytes()"
+	case smartcontract.StringType:
+		return item + ".as_str()"
+	case smartcontract.Hash160Type:
+		return item + ".as_uint160()"
+	case smartcontract.Hash256Type:
+		return item + ".as_uint256()"
+	case smartcontract.PublicKeyType:
+		return item + ".as_public_key()"
+	case smartcontract.ArrayType:
+		if et.Name != "" {
+			return generators.StructName(et.Name) + ".from_stack_item(" + item + ")"
+		}
+		if et.Value != nil {
+			v := fmt.Sprintf("item%d", depth)
+			return fmt.Sprintf("[%s for %s in %s.as_list()]", stackItemToPython(v, *et.Value, depth+1), v, item)
+		}
+		return item + ".as_list()"
+	case smartcontract.MapType:
+		if et.Value != nil {
+			k, v := fmt.Sprintf("key%d", depth), fmt.Sprintf("value%d", depth)
+			return fmt.Sprintf("{%s: %s for %s, %s in %s.as_dict().items()}",
+				stackItemToPython(k, binding.ExtendedType{Base: et.Key}, depth+1), stackItemToPython(v, *et.Value, depth+1),
+				k, v, item)
+		}
+		return item + ".as_dict()"
+	default:
+		panic(fmt.Sprintf("unknown type: %T %s", et.Base, et.Base))
+	}
+}
+
+func mambaParameter(name, typ string, et *binding.ExtendedType) string {
+	return pythonToScriptArgument(name, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// pythonToScriptArgument returns the expression converting the variable of the type to a value the script builder can
+// push. Values that need no conversion are returned as they are
+func pythonToScriptArgument(name string, et binding.ExtendedType, depth int) string {
+	switch {
+	case et.Base == smartcontract.Hash160Type:
+		return "_check_address_and_convert(" + name + ")"
+	case et.Base == smartcontract.ArrayType && et.Name != "":
+		return name + ".to_list()"
+	case et.Base == smartcontract.ArrayType && et.Value != nil:
+		v := fmt.Sprintf("item%d", depth)
+		if arg := pythonToScriptArgument(v, *et.Value, depth+1); arg != v {
+			return fmt.Sprintf("[%s for %s in %s]", arg, v, name)
+		}
+	case et.Base == smartcontract.MapType && et.Value != nil:
+		k, v := fmt.Sprintf("key%d", depth), fmt.Sprintf("value%d", depth)
+		key := pythonToScriptArgument(k, binding.ExtendedType{Base: et.Key}, depth+1)
+		if value := pythonToScriptArgument(v, *et.Value, depth+1); key != k || value != v {
+			return fmt.Sprintf("{%s: %s for %s, %s in %s.items()}", key, value, k, v, name)
+		}
+	}
+	return name
+}
@@ -3,12 +3,9 @@ package python
 import (
 	"fmt"
 	"os"
-	"strings"
-	"text/template"
 
 	"cpm/generators"
 
-	"github.com/iancoleman/strcase"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
 	log "github.com/sirupsen/logrus"
 )
@@ -38,9 +35,36 @@ const pythonSrcTmpl = `
        {{- end}}) -> {{if .ReturnType }}{{ .ReturnType }}: {{ else }} None: {{ end }}
         pass
 {{- end -}}
+{{- define "EVENT" }}
+on_{{ .Name }} = CreateNewEvent(
+    [
+    {{- range $arg := .Arguments }}
+        ('{{ .Name }}', {{ .Type }}),
+    {{- end }}
+    ],
+    '{{ .NameABI }}'
+)
+{{- end -}}
+{{- define "STRUCT" }}
+class {{ .Name }}:
+{{- range $f := .Fields }}
+    {{ .Name }}: {{ .Type }}
+{{- else }}
+    pass
+{{- end }}
+{{- end -}}
 from boa3.sc.types import UInt160, UInt256, ECPoint
-from boa3.sc.compile_time import contract, display_name
+from boa3.sc.compile_time import contract, display_name{{ if .Events }}, CreateNewEvent{{ end }}
 from typing import cast, Any
+{{- range $s := .Structs}}
+
+{{ template "STRUCT" $s }}
+{{- end}}
+{{- if .Events }}
+{{ range $e := .Events}}
+{{ template "EVENT" $e }}
+{{- end}}
+{{- end}}
 
 
 @contract('{{ .Hash }}')
@@ -57,14 +81,19 @@ func generateOnchainSDK(cfg *generators.GenerateCfg) error {
 		return err
 	}
 
-	cfg.MethodNameConverter = strcase.ToSnake
+	cfg.MethodNameConverter = pythonName
 	cfg.ParamTypeConverter = scTypeToPython
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return fmt.Sprintf("list[%s]", value) }
+	cfg.MapTypeConverter = func(key, value string) string { return fmt.Sprintf("dict[%s, %s]", key, value) }
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
+	escapeArguments(&ctr)
+	ctr.ConvertEventNames(cfg.MethodNameConverter)
 
-	tmp, err := template.New("generate").Parse(pythonSrcTmpl)
+	tmp, err := generators.ParseTemplate(cfg, "contract", pythonSrcTmpl, nil)
 	if err != nil {
 		return fmt.Errorf("failed to parse Python source template: %v", err)
 	}
@@ -78,7 +107,7 @@ func generateOnchainSDK(cfg *generators.GenerateCfg) error {
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %v", err)
 	}
-	sdkLocation := wd + "/" + cfg.SdkDestination + generators.UpperFirst(cfg.Manifest.Name)
+	sdkLocation := wd + "/" + cfg.SdkDestination + generators.UpperFirst(cfg.Name())
 	log.Infof("Created SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, sdkLocation, cfg.ContractHash.StringLE())
 
 	return nil
@@ -86,7 +115,7 @@ func generateOnchainSDK(cfg *generators.GenerateCfg) error {
 
 // create the Python package structure and set the ContractOutput to the open file handle
 func createPythonPackage(cfg *generators.GenerateCfg) error {
-	sdkDir := cfg.SdkDestination + strings.ToLower(cfg.Manifest.Name)
+	sdkDir := OutputPath(cfg.SdkDestination, cfg.Name(), generators.SDKOnChain)
 	err := os.MkdirAll(sdkDir, 0755)
 	if err != nil {
 		return fmt.Errorf("can't create on-chain directory %s: %w", sdkDir, err)
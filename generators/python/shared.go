@@ -1,6 +1,37 @@
 package python
 
-import "cpm/generators"
+import (
+	"cpm/generators"
+	"slices"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+func init() {
+	generators.Register(generator{})
+}
+
+type generator struct{}
+
+func (generator) Language() string    { return "python" }
+func (generator) DisplayName() string { return "Python" }
+func (generator) SDKTypes() []string  { return []string{generators.SDKOnChain, generators.SDKOffChain} }
+
+func (generator) OutputPath(destination, contractName, sdkType string) string {
+	return OutputPath(destination, contractName, sdkType)
+}
+
+func (generator) Templates(sdkType string) map[string]string {
+	if sdkType == generators.SDKOnChain {
+		return map[string]string{"contract": pythonSrcTmpl}
+	}
+	return map[string]string{"contract": pythonOffChainSrcTmpl}
+}
+
+func (generator) Generate(cfg *generators.GenerateCfg, sdkType string) error {
+	return GenerateSDK(cfg, sdkType)
+}
 
 func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 	if sdkType == generators.SDKOnChain {
@@ -9,3 +40,39 @@ func GenerateSDK(cfg *generators.GenerateCfg, sdkType string) error {
 		return generateOffchainSDK(cfg)
 	}
 }
+
+// OutputPath returns the package directory the SDK of the contract is written to
+func OutputPath(destination, contractName, sdkType string) string {
+	name := strings.ToLower(contractName)
+	if sdkType == generators.SDKOffChain {
+		name = strings.ReplaceAll(name, " ", "_")
+	}
+	return destination + name
+}
+
+var pythonKeywords = []string{"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+	"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is",
+	"lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield"}
+
+// pythonName converts a method or field name to snake case. Keywords get a trailing underscore, i.e. 'from_'
+func pythonName(s string) string {
+	return pythonIdentifier(strcase.ToSnake(s))
+}
+
+// escapeArguments appends an underscore to method arguments that are keywords, i.e. 'from_', like pythonName does for
+// method and field names
+func escapeArguments(ctr *generators.ContractTmpl) {
+	for _, m := range ctr.Methods {
+		for i := range m.Arguments {
+			m.Arguments[i].Name = pythonIdentifier(m.Arguments[i].Name)
+		}
+	}
+}
+
+// pythonIdentifier appends an underscore to keywords, so that the name can be used as identifier
+func pythonIdentifier(name string) string {
+	if slices.Contains(pythonKeywords, name) {
+		return name + "_"
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package generators
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/iancoleman/strcase"
+	log "github.com/sirupsen/logrus"
+)
+
+// TemplateExt is the file extension of template overrides, which are named '<template name>.tmpl'
+const TemplateExt = ".tmpl"
+
+// FuncMap returns the functions available in the templates of all languages. Languages add their own functions
+//
+//   - UpperFirst upper cases the first letter of a string, i.e. for class names
+//   - ToCamel converts a name to upper camel case, i.e. 'TransferDone' for the class of event 'transfer_done'
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"UpperFirst": UpperFirst,
+		"ToCamel":    strcase.ToCamel,
+	}
+}
+
+// ParseTemplate parses the built-in template with the functions of FuncMap and funcs. If cfg.TemplateDir contains a
+// file named after the template, it is parsed on top of the built-in template: a file with a body replaces the built-in
+// template, a file of only '{{ define }}' blocks replaces just the blocks it defines
+func ParseTemplate(cfg *GenerateCfg, name, text string, funcs template.FuncMap) (*template.Template, error) {
+	tmp, err := template.New(name).Funcs(FuncMap()).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TemplateDir == "" {
+		return tmp, nil
+	}
+
+	path := filepath.Join(cfg.TemplateDir, name+TemplateExt)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return tmp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Parse(string(data)); err != nil {
+		return nil, fmt.Errorf("template %s: %w", path, err)
+	}
+	log.Debugf("Using template %s", path)
+	return tmp, nil
+}
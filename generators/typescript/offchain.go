@@ -10,6 +10,7 @@ import (
 
 	"github.com/iancoleman/strcase"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -45,13 +46,30 @@ export function {{ .Name }}API(scriptHash: string{{if .Arguments}}, params: { {{
 		scriptHash,
 		operation: '{{ .NameABI }}',
 		args: [{{range $index, $arg := .Arguments -}}
-			parser.formatRpcArgument(params.{{- .Name}}, { type: '{{ .TypeABI }}' }),
+			{{ NeonParameter (printf "params.%s" .Name) .TypeABI .ExtendedType }},
 		{{- end}}
 		],
 	}
 }
 {{- end -}}
-import { Neo3Parser, ContractInvocation} from "@cityofzion/neon-dappkit-types"
+{{- define "STRUCT" }}
+export class {{ .Name }} {
+	constructor({{ range $index, $f := .Fields }}{{ if ne $index 0 }}, {{ end }}public {{ .Name }}: {{ .Type }}{{ end }}) {}
+
+	static fromArray(values: any[], parser: Neo3Parser): {{ .Name }} {
+		return new {{ .Name }}({{ range $index, $f := .Fields }}{{ if ne $index 0 }}, {{ end }}{{ NeonDecode "parser" (printf "values[%d]" $index) .TypeABI .ExtendedType }}{{ end }})
+	}
+
+	toArgument(parser: Neo3Parser): Arg {
+		return { type: 'Array', value: [{{ range $index, $f := .Fields }}{{ if ne $index 0 }}, {{ end }}{{ NeonParameter (printf "this.%s" .Name) .TypeABI .ExtendedType }}{{ end }}] }
+	}
+}
+{{- end -}}
+import { Neo3Parser, ContractInvocation{{ if .Structs }}, Arg{{ end }}} from "@cityofzion/neon-dappkit-types"
+
+{{- range $s := .Structs}}
+{{ template "STRUCT" $s }}
+{{- end}}
 
 {{- range $m := .Methods}}
 {{ template "APIMETHOD" $m -}}
@@ -114,7 +132,7 @@ const typescriptSrcClassTmpl = `
 		}
 		{{- if ne .ReturnType "void"}}
 		
-		return this.config.parser.parseRpcResponse(res.stack[0], { type: '{{ .ReturnTypeABI }}' })
+		return {{ NeonDecode "this.config.parser" "res.stack[0]" .ReturnTypeABI .ReturnExtendedType }}
 		{{- end}}
 	}
 {{- end -}}
@@ -131,24 +149,27 @@ const typescriptSrcClassTmpl = `
 
 		const txResult = await this.config.eventListener.waitForApplicationLog(txId)
 		this.config.eventListener.confirmTransaction(
-			txResult, {contract: this.config.scriptHash, eventname: '{{ .Name }}'}
+			txResult, {contract: this.config.scriptHash, eventname: '{{ .NameABI }}'}
 		)
 	}
 
 	listen{{ UpperFirst .Name }}Event(callback: Neo3EventListenerCallback): void{
 		if (!this.config.eventListener) throw new Error('EventListener not provided')
 		
-		this.config.eventListener.addEventListener(this.config.scriptHash, '{{ .Name }}', callback)
+		this.config.eventListener.addEventListener(this.config.scriptHash, '{{ .NameABI }}', callback)
 	}
 
 	remove{{ UpperFirst .Name }}EventListener(callback: Neo3EventListenerCallback): void{
 		if (!this.config.eventListener) throw new Error('EventListener not provided')
 		
-		this.config.eventListener.removeEventListener(this.config.scriptHash, '{{ .Name }}', callback)
+		this.config.eventListener.removeEventListener(this.config.scriptHash, '{{ .NameABI }}', callback)
 	}
 {{- end -}}
 import { Neo3EventListener, Neo3EventListenerCallback, Neo3Invoker, Neo3Parser, TypeChecker } from "@cityofzion/neon-dappkit-types"
 import * as Invocation from './api'
+{{- if .Structs }}
+import { {{ range $index, $s := .Structs }}{{ if ne $index 0 }}, {{ end }}{{ .Name }}{{ end }} } from './api'
+{{- end }}
 
 export type SmartContractConfig = {
   scriptHash: string;
@@ -187,32 +208,49 @@ export class {{ .ContractName }}{
 const typescriptSrcIndexTmpl = `export * from './{{ .ContractName }}'
 export * from './api'`
 
+// funcMap are the functions of the templates in addition to generators.FuncMap
+//
+//   - NeonParameter returns the expression converting a variable of the ABI or extended type to an RPC argument. Fields
+//     of structs and values of typed arrays and maps are formatted by their own type
+//   - NeonDecode returns the expression converting an RPC stack item to the ABI or extended type using the parser
+var funcMap = template.FuncMap{
+	"NeonParameter": neonParameter,
+	"NeonDecode":    neonDecode,
+}
+
 func GenerateTypeScriptSDK(cfg *generators.GenerateCfg) error {
 	cfg.MethodNameConverter = strcase.ToLowerCamel
 	cfg.ParamTypeConverter = scTypeToTypeScript
+	cfg.StructTypeConverter = generators.StructName
+	cfg.ArrayTypeConverter = func(value string) string { return value + "[]" }
+	cfg.MapTypeConverter = func(key, value string) string {
+		if key != "number" {
+			key = "string"
+		}
+		return fmt.Sprintf("Record<%s, %s>", key, value)
+	}
 	ctr, err := generators.TemplateFromManifest(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest into contract template: %v", err)
 	}
 
-	folderName := strings.ToLower(strings.Join(regexp.MustCompile(`[\W]+`).Split(cfg.Manifest.Name, -1), "-"))
-	sdkDir := cfg.SdkDestination + folderName
+	sdkDir := OutputPath(cfg.SdkDestination, cfg.Name())
 	err = os.MkdirAll(sdkDir, 0755)
 	if err != nil {
 		return fmt.Errorf("can't create directory %s: %w", sdkDir, err)
 	}
 
-	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, "api", typescriptSrcApiTmpl)
+	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, "api", "api", typescriptSrcApiTmpl)
 	if err != nil {
 		return err
 	}
 
-	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, ctr.ContractName, typescriptSrcClassTmpl)
+	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, ctr.ContractName, "class", typescriptSrcClassTmpl)
 	if err != nil {
 		return err
 	}
 
-	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, "index", typescriptSrcIndexTmpl)
+	err = generateTypeScriptSdkFile(cfg, ctr, sdkDir, "index", "index", typescriptSrcIndexTmpl)
 	if err != nil {
 		return err
 	}
@@ -221,24 +259,25 @@ func GenerateTypeScriptSDK(cfg *generators.GenerateCfg) error {
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %v", err)
 	}
-	sdkLocation := wd + "/" + cfg.SdkDestination + folderName
+	sdkLocation := wd + "/" + sdkDir
 	log.Infof("Created SDK for contract '%s' at %s with contract hash 0x%s", cfg.Manifest.Name, sdkLocation, cfg.ContractHash.StringLE())
 
 	return nil
 }
 
-func generateTypeScriptSdkFile(cfg *generators.GenerateCfg, ctr generators.ContractTmpl, sdkDir string, fileName string, templateString string) error {
+// OutputPath returns the directory the SDK of the contract is written to
+func OutputPath(destination, contractName string) string {
+	return destination + strings.ToLower(strings.Join(regexp.MustCompile(`[\W]+`).Split(contractName, -1), "-"))
+}
+
+func generateTypeScriptSdkFile(cfg *generators.GenerateCfg, ctr generators.ContractTmpl, sdkDir string, fileName string, templateName string, templateString string) error {
 	err := createTypeScriptSdkFile(cfg, sdkDir, fileName)
 	defer cfg.ContractOutput.Close()
 	if err != nil {
 		return err
 	}
 
-	funcMap := template.FuncMap{
-		"UpperFirst": generators.UpperFirst,
-	}
-
-	tmp, err := template.New("generate").Funcs(funcMap).Parse(templateString)
+	tmp, err := generators.ParseTemplate(cfg, templateName, templateString, funcMap)
 	if err != nil {
 		return fmt.Errorf("failed to parse TypeScript source %s file template: %v", fileName, err)
 	}
@@ -292,3 +331,49 @@ func scTypeToTypeScript(typ smartcontract.ParamType) string {
 		panic(fmt.Sprintf("unknown type: %T %s", typ, typ))
 	}
 }
+
+func neonDecode(parser, value, typ string, et *binding.ExtendedType) string {
+	return stackItemToTypeScript(parser, value, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// stackItemToTypeScript returns the expression converting the RPC stack item to the type. Structs, typed arrays and
+// typed maps are converted item by item, so that every value is parsed by its own type. depth numbers the variables of
+// nested functions
+func stackItemToTypeScript(parser, value string, et binding.ExtendedType, depth int) string {
+	switch {
+	case et.Base == smartcontract.ArrayType && et.Name != "":
+		return fmt.Sprintf("%s.fromArray(%s.value, %s)", generators.StructName(et.Name), value, parser)
+	case et.Base == smartcontract.ArrayType && et.Value != nil:
+		v := fmt.Sprintf("item%d", depth)
+		return fmt.Sprintf("%s.value.map((%s: any) => %s)", value, v, stackItemToTypeScript(parser, v, *et.Value, depth+1))
+	case et.Base == smartcontract.MapType && et.Value != nil:
+		e := fmt.Sprintf("entry%d", depth)
+		return fmt.Sprintf("Object.fromEntries(%s.value.map((%s: any) => [%s, %s]))", value, e,
+			stackItemToTypeScript(parser, e+".key", binding.ExtendedType{Base: et.Key}, depth+1),
+			stackItemToTypeScript(parser, e+".value", *et.Value, depth+1))
+	}
+	return fmt.Sprintf("%s.parseRpcResponse(%s, { type: '%s' })", parser, value, et.Base)
+}
+
+func neonParameter(name, typ string, et *binding.ExtendedType) string {
+	return typeScriptToArgument(name, generators.ExtendedTypeOf(typ, et), 0)
+}
+
+// typeScriptToArgument returns the expression converting the variable of the type to an RPC argument. Structs, typed
+// arrays and typed maps are converted item by item, so that every value is formatted by its own type, i.e. a Hash160
+// field as Hash160 instead of String
+func typeScriptToArgument(name string, et binding.ExtendedType, depth int) string {
+	switch {
+	case et.Base == smartcontract.ArrayType && et.Name != "":
+		return name + ".toArgument(parser)"
+	case et.Base == smartcontract.ArrayType && et.Value != nil:
+		v := fmt.Sprintf("item%d", depth)
+		return fmt.Sprintf("{ type: 'Array', value: %s.map((%s: any) => %s) }", name, v, typeScriptToArgument(v, *et.Value, depth+1))
+	case et.Base == smartcontract.MapType && et.Value != nil:
+		k, v := fmt.Sprintf("key%d", depth), fmt.Sprintf("value%d", depth)
+		return fmt.Sprintf("{ type: 'Map', value: Object.entries(%s).map(([%s, %s]: [string, any]) => ({ key: %s, value: %s })) }",
+			name, k, v, typeScriptToArgument(k, binding.ExtendedType{Base: et.Key}, depth+1),
+			typeScriptToArgument(v, *et.Value, depth+1))
+	}
+	return fmt.Sprintf("parser.formatRpcArgument(%s, { type: '%s' })", name, et.Base)
+}
@@ -0,0 +1,25 @@
+package typescript
+
+import "cpm/generators"
+
+func init() {
+	generators.Register(generator{})
+}
+
+type generator struct{}
+
+func (generator) Language() string    { return "ts" }
+func (generator) DisplayName() string { return "TypeScript" }
+func (generator) SDKTypes() []string  { return []string{generators.SDKOffChain} }
+
+func (generator) OutputPath(destination, contractName, sdkType string) string {
+	return OutputPath(destination, contractName)
+}
+
+func (generator) Templates(sdkType string) map[string]string {
+	return map[string]string{"api": typescriptSrcApiTmpl, "class": typescriptSrcClassTmpl, "index": typescriptSrcIndexTmpl}
+}
+
+func (generator) Generate(cfg *generators.GenerateCfg, sdkType string) error {
+	return GenerateTypeScriptSDK(cfg)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const DEFAULT_LOCK_FILE = "cpm.lock"
+
+const lockFileHeader = "# This file is generated by cpm. Do not edit it manually\n"
+
+// LockEntry records the exact contract state that was pulled from the source network
+type LockEntry struct {
+	Label          string       `yaml:"label"`
+	ScriptHash     util.Uint160 `yaml:"script-hash"`
+	SourceNetwork  string       `yaml:"source-network"`
+	NetworkMagic   uint32       `yaml:"network-magic"`
+	BlockHeight    uint32       `yaml:"block-height"`
+	UpdateCounter  uint16       `yaml:"update-counter"`
+	NefChecksum    uint32       `yaml:"nef-checksum"`
+	ManifestDigest string       `yaml:"manifest-digest"`
+	// Abi holds the method and event signatures of the manifest to report ABI changes
+	Abi []string `yaml:"abi,omitempty"`
+}
+
+type LockFile struct {
+	Contracts []LockEntry `yaml:"contracts"`
+
+	mu sync.Mutex
+}
+
+// lockFilePath returns the path of the lock file, which lives next to the config file
+func lockFilePath() string {
+	return cfg.resolvePath(DEFAULT_LOCK_FILE)
+}
+
+// loadLockFile reads the lock file. Returns an empty lock file if it does not exist
+func loadLockFile(path string) (*LockFile, error) {
+	l := &LockFile{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+	return l, nil
+}
+
+func (l *LockFile) get(scriptHash util.Uint160) *LockEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.Contracts {
+		if l.Contracts[i].ScriptHash.Equals(scriptHash) {
+			entry := l.Contracts[i]
+			return &entry
+		}
+	}
+	return nil
+}
+
+func (l *LockFile) set(entry LockEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.Contracts {
+		if l.Contracts[i].ScriptHash.Equals(entry.ScriptHash) {
+			l.Contracts[i] = entry
+			return
+		}
+	}
+	l.Contracts = append(l.Contracts, entry)
+}
+
+// retain removes all entries for contracts that are no longer in the config
+func (l *LockFile) retain(contracts []ContractConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []LockEntry
+	for _, e := range l.Contracts {
+		for _, c := range contracts {
+			if c.ScriptHash.Equals(e.ScriptHash) {
+				entries = append(entries, e)
+				break
+			}
+		}
+	}
+	l.Contracts = entries
+}
+
+func (l *LockFile) saveToDisk(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(lockFileHeader), data...), 0644)
+}
+
+// verify returns an error describing the differences if the source state in other no longer matches the locked state
+func (e *LockEntry) verify(other *LockEntry) error {
+	if e.NetworkMagic != other.NetworkMagic {
+		return fmt.Errorf("network magic changed from %d to %d", e.NetworkMagic, other.NetworkMagic)
+	}
+	if e.UpdateCounter != other.UpdateCounter {
+		return fmt.Errorf("contract was updated (update counter changed from %d to %d)", e.UpdateCounter, other.UpdateCounter)
+	}
+	if e.NefChecksum != other.NefChecksum {
+		return fmt.Errorf("NEF checksum changed from %d to %d", e.NefChecksum, other.NefChecksum)
+	}
+	if e.ManifestDigest != other.ManifestDigest {
+		return fmt.Errorf("manifest digest changed from %s to %s", e.ManifestDigest, other.ManifestDigest)
+	}
+	return nil
+}
+
+// fetchLockEntry returns the current source state of the contract as lock entry from the first host that responds
+func fetchLockEntry(c *ContractConfig, hosts []string) (*LockEntry, error) {
+	contract, err := fetchSourceState(c.ScriptHash, hosts, c.SourceHeight, false)
+	if err != nil {
+		return nil, err
+	}
+	return newLockEntry(c, contract)
+}
+
+// fetchSourceState returns the state of the contract at the block height, or the latest state if height is nil, from
+// the first host that responds. The storage is included if withStorage is set
+func fetchSourceState(scriptHash util.Uint160, hosts []string, height *uint32, withStorage bool) (*cachedContract, error) {
+	var contract *cachedContract
+	err := tryHosts(hosts, log.DebugLevel, func(host string) error {
+		var err error
+		contract, err = loadContract(scriptHash, host, height, withStorage, "contract state")
+		return err
+	})
+	return contract, err
+}
+
+// newLockEntry returns the lock entry that records the contract state
+func newLockEntry(c *ContractConfig, contract *cachedContract) (*LockEntry, error) {
+	digest, err := manifestDigest(&contract.State.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LockEntry{
+		Label:          c.Label,
+		ScriptHash:     c.ScriptHash,
+		SourceNetwork:  *c.SourceNetwork,
+		NetworkMagic:   contract.Magic,
+		BlockHeight:    contract.Height,
+		UpdateCounter:  contract.State.UpdateCounter,
+		NefChecksum:    contract.State.NEF.Checksum,
+		ManifestDigest: digest,
+		Abi:            abiSignatures(&contract.State.Manifest),
+	}, nil
+}
+
+// manifestDigest returns the SHA256 digest of the JSON encoded manifest
+func manifestDigest(m *manifest.Manifest) (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LockFile(t *testing.T) {
+	entry := LockEntry{
+		Label:          "sample",
+		ScriptHash:     util.Uint160{1, 2, 3},
+		SourceNetwork:  "mainnet",
+		NetworkMagic:   860833102,
+		BlockHeight:    100,
+		UpdateCounter:  1,
+		NefChecksum:    3884080072,
+		ManifestDigest: "sha256:00",
+	}
+
+	t.Run("should round trip through disk", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), DEFAULT_LOCK_FILE)
+		l := &LockFile{}
+		l.set(entry)
+		require.NoError(t, l.saveToDisk(path))
+
+		loaded, err := loadLockFile(path)
+		require.NoError(t, err)
+		if assert.Len(t, loaded.Contracts, 1) {
+			assert.Equal(t, entry, loaded.Contracts[0])
+		}
+	})
+
+	t.Run("missing lock file is empty", func(t *testing.T) {
+		l, err := loadLockFile(filepath.Join(t.TempDir(), DEFAULT_LOCK_FILE))
+		require.NoError(t, err)
+		assert.Empty(t, l.Contracts)
+	})
+
+	t.Run("verify detects contract updates", func(t *testing.T) {
+		current := entry
+		current.BlockHeight = 200
+		assert.NoError(t, entry.verify(&current))
+
+		current.UpdateCounter = 2
+		err := entry.verify(&current)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "update counter changed from 1 to 2")
+	})
+}
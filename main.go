@@ -1,25 +1,32 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"cpm/generators"
-	"cpm/generators/csharp"
-	"cpm/generators/golang"
-	"cpm/generators/java"
-	"cpm/generators/python"
-	"cpm/generators/typescript"
+	_ "cpm/generators/csharp"
+	_ "cpm/generators/golang"
+	_ "cpm/generators/java"
+	_ "cpm/generators/python"
+	_ "cpm/generators/typescript"
 
-	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/binding"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
 	"github.com/nspcc-dev/neo-go/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -33,6 +40,7 @@ var (
 	LOG_DEBUG = "DEBUG"
 
 	DEFAULT_CONFIG_FILE = "cpm.yaml"
+	CONFIG_ENV          = "CPM_CONFIG"
 
 	version = "dev"
 )
@@ -56,7 +64,19 @@ OPTIONS:{{template "visibleFlagTemplate" .}}{{end}}
 func main() {
 	log.SetOutput(os.Stdout)
 
-	app := &cli.App{
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	appCtx = ctx
+
+	if err := newApp().RunContext(ctx, os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newApp() *cli.App {
+	generators.RegisterPathPlugins()
+
+	return &cli.App{
 		Usage: "Contract Package Manager",
 		Flags: []cli.Flag{
 			&cli.GenericFlag{
@@ -67,8 +87,14 @@ func main() {
 					Enum: []string{LOG_INFO, LOG_DEBUG},
 				},
 			},
+			&cli.StringFlag{Name: "config", Aliases: []string{"C"}, Usage: "Path of the config file. Defaults to $CPM_CONFIG or the first cpm.yaml in the current or a parent directory", Required: false},
+			&cli.BoolFlag{Name: "offline", Usage: "Serve all contract information from the local cache instead of the source network", Required: false, Value: false, DisableDefaultText: true},
 		},
 		Before: beforeAction,
+		After: func(*cli.Context) error {
+			clients.close()
+			return nil
+		},
 		Action: func(cCtx *cli.Context) error {
 			if cCtx.NArg() == 0 {
 				cli.ShowAppHelpAndExit(cCtx, 0)
@@ -82,10 +108,48 @@ func main() {
 				Action: handleCliInit,
 			},
 			{
-				Name:   "run",
-				Usage:  "Download all contracts from cpm.yaml and generate SDKs where specified",
+				Name:  "run",
+				Usage: "Download all contracts from cpm.yaml and generate SDKs where specified",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "with-deps", Usage: "Also download all contracts the configured contracts depend on and add them to cpm.yaml", Required: false, Value: false, DisableDefaultText: true},
+					&cli.IntFlag{Name: "jobs", Aliases: []string{"j"}, Usage: "Number of contracts to process concurrently", Required: false, Value: 1},
+					&cli.BoolFlag{Name: "frozen", Usage: "Download contracts at the block height recorded in cpm.lock and fail if their state does not match it. Does not update cpm.lock", Required: false, Value: false, DisableDefaultText: true},
+				},
 				Action: handleCliRun,
 			},
+			{
+				Name:   "outdated",
+				Usage:  "Show contracts that changed on the source network since they were recorded in cpm.lock",
+				Action: handleCliOutdated,
+			},
+			{
+				Name:      "update",
+				Usage:     "Download and generate SDKs for outdated contracts again and update cpm.lock",
+				ArgsUsage: "[label|script hash]",
+				Action:    handleCliUpdate,
+			},
+			{
+				Name:      "add",
+				Usage:     "Add a contract to cpm.yaml, labeled with the name from its manifest",
+				ArgsUsage: "<script hash|native contract name|NNS domain>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "network", Aliases: []string{"n"}, Usage: "Source network label. Defaults to 'contract-source-network'", Required: false},
+					&cli.BoolFlag{Name: "no-download", Usage: "Do not download the contract to the local chain", Required: false, Value: false, DisableDefaultText: true},
+					&cli.StringSliceFlag{Name: generators.SDKOnChain, Usage: "Languages to generate on-chain SDKs for, i.e. go,python", Required: false},
+					&cli.StringSliceFlag{Name: generators.SDKOffChain, Usage: "Languages to generate off-chain SDKs for, i.e. ts", Required: false},
+					&cli.BoolFlag{Name: "run", Usage: "Download the contract and generate its SDKs right away", Required: false, Value: false, DisableDefaultText: true},
+				},
+				Action: handleCliAdd,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Remove a contract from cpm.yaml and delete its generated SDKs",
+				ArgsUsage: "<label|script hash>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "keep-sdk", Usage: "Do not delete the generated SDKs", Required: false, Value: false, DisableDefaultText: true},
+				},
+				Action: handleCliRemove,
+			},
 			{
 				Name:  "download",
 				Usage: "Download contract or manifest",
@@ -94,10 +158,13 @@ func main() {
 						Name:  "contract",
 						Usage: "Download a single contract",
 						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash", Required: true},
+							&cli.StringFlag{Name: "c", Usage: "Contract script hash, native contract name (i.e. GasToken) or NNS domain (i.e. flamingo.neo)", Required: true},
 							&cli.StringFlag{Name: "n", Usage: "Source network label. Searches cpm.yaml for the network by label to find the host", Required: false},
 							&cli.StringFlag{Name: "N", Usage: "Source network host", Required: false},
 							&cli.StringFlag{Name: "i", Usage: "Neo express config file", Required: false, DefaultText: "default.neo-express"},
+							&cli.StringFlag{Name: "d", Usage: "Contract destination. Overrides 'contract-destination' in cpm.yaml", Required: false},
+							&cli.Uint64Flag{Name: "height", Usage: "Download the contract state at this block height instead of the latest state", Required: false},
+							&cli.BoolFlag{Name: "with-deps", Usage: "Also download all contracts the contract depends on", Required: false, Value: false, DisableDefaultText: true},
 							&cli.BoolFlag{Name: "s", Usage: "Save contract to the 'contracts' section of cpm.yaml", Required: false, Value: false, DisableDefaultText: true},
 						},
 						Action: handleCliDownloadContract,
@@ -106,7 +173,7 @@ func main() {
 						Name:  "manifest",
 						Usage: "Download the contract manifest",
 						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash", Required: true},
+							&cli.StringFlag{Name: "c", Usage: "Contract script hash, native contract name (i.e. GasToken) or NNS domain (i.e. flamingo.neo)", Required: true},
 							&cli.StringFlag{Name: "n", Usage: "Source network label. Searches cpm.yaml for the network by label to find the host", Required: false},
 							&cli.StringFlag{Name: "N", Usage: "Source network host", Required: false},
 							&cli.BoolFlag{Name: "s", Usage: "Save contract to the 'contracts' section of cpm.yaml", Required: false, Value: false, DisableDefaultText: true},
@@ -127,90 +194,50 @@ func main() {
 				Name:               "generate",
 				Usage:              "Generate SDK from manifest",
 				CustomHelpTemplate: GenerateCommandHelpTemplate,
+				Subcommands:        generateCommands(),
+				// the global '--config' flag is only known now, so the plugins of the config are added here
+				Before: func(cCtx *cli.Context) error {
+					if err := registerConfigGenerators(); err != nil {
+						return err
+					}
+					cCtx.Command.Subcommands = generateCommands()
+					return nil
+				},
+			},
+			{
+				Name:  "templates",
+				Usage: "Customize the templates SDKs are generated from",
 				Subcommands: []*cli.Command{
 					{
-						Name:  LANG_GO,
-						Usage: "Generate a SDK for use with Golang",
-						Action: func(c *cli.Context) error {
-							return handleCliGenerate(c, LANG_GO)
-						},
+						Name:      "export",
+						Usage:     "Write the built-in templates to a folder as starting point for 'templates' in cpm.yaml",
+						ArgsUsage: "[language...]",
 						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
-							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
-							&cli.GenericFlag{
-								Name:     "t",
-								Usage:    "SDK type",
-								Required: true,
-								Value: &EnumValue{
-									Enum: []string{generators.SDKOffChain, generators.SDKOnChain},
-								},
-							},
-						},
-					},
-					{
-						Name:  LANG_PYTHON,
-						Usage: "Generate a SDK for use with Python",
-						Action: func(c *cli.Context) error {
-							return handleCliGenerate(c, LANG_PYTHON)
-						},
-						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
-							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
-							&cli.GenericFlag{
-								Name:     "t",
-								Usage:    "SDK type",
-								Required: true,
-								Value: &EnumValue{
-									Enum: []string{generators.SDKOffChain, generators.SDKOnChain},
-								},
-							},
+							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false, Value: "templates"},
+							&cli.BoolFlag{Name: "force", Usage: "Overwrite existing template files", Required: false, Value: false, DisableDefaultText: true},
 						},
+						Action: handleCliTemplatesExport,
 					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Inspect and validate cpm.yaml",
+				Subcommands: []*cli.Command{
 					{
-						Name:  LANG_JAVA,
-						Usage: "Generate a SDK for use with Java",
-						Action: func(c *cli.Context) error {
-							return handleCliGenerate(c, LANG_JAVA)
-						},
-						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
-							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
-							&cli.GenericFlag{
-								Name:     "t",
-								Usage:    "SDK type",
-								Required: true,
-								Value: &EnumValue{
-									Enum: []string{generators.SDKOffChain, generators.SDKOnChain},
-								},
-							},
-						},
+						Name:   "show",
+						Usage:  "Print the config with environment variables resolved and secrets redacted",
+						Action: handleCliConfigShow,
 					},
 					{
-						Name:  LANG_CSHARP,
-						Usage: "Generate an on-chain SDK for use with C#",
-						Action: func(c *cli.Context) error {
-							return handleCliGenerate(c, LANG_CSHARP)
-						},
-						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
-							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
-						},
+						Name:   "validate",
+						Usage:  "Check the config for unknown fields, undefined networks, unsupported languages and missing files",
+						Action: handleCliConfigValidate,
 					},
 					{
-						Name:  LANG_TYPESCRIPT,
-						Usage: "Generate an off-chain SDK for use with TypeScript",
-						Action: func(c *cli.Context) error {
-							return handleCliGenerate(c, LANG_TYPESCRIPT)
-						},
-						Flags: []cli.Flag{
-							&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
-							&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
-							&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
-						},
+						Name:   "schema",
+						Usage:  "Print the JSON Schema of cpm.yaml for editor completion",
+						Action: handleCliConfigSchema,
 					},
 				},
 			},
@@ -221,16 +248,54 @@ func main() {
 			},
 		},
 	}
+}
 
-	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+// generateCommands returns a 'generate' subcommand for every registered generator
+func generateCommands() []*cli.Command {
+	var commands []*cli.Command
+	for _, g := range generators.All() {
+		flags := []cli.Flag{
+			&cli.StringFlag{Name: "m", Usage: "Path to contract manifest.json", Required: true},
+			&cli.StringFlag{Name: "c", Usage: "Contract script hash if known", Required: false},
+			&cli.StringFlag{Name: "o", Usage: "Output folder", Required: false},
+			&cli.StringFlag{Name: "b", Usage: "Path to a neo-go binding config with the extended types of the contract", Required: false},
+		}
+		if templates := g.Templates(g.SDKTypes()[0]); templates != nil {
+			flags = append(flags, &cli.StringFlag{Name: "templates", Usage: "Folder with templates that override the built-in templates", Required: false})
+		}
+		usage := fmt.Sprintf("Generate a SDK for use with %s", g.DisplayName())
+		if types := g.SDKTypes(); len(types) > 1 {
+			flags = append(flags, &cli.GenericFlag{
+				Name:     "t",
+				Usage:    "SDK type",
+				Required: true,
+				Value: &EnumValue{
+					Enum: slices.Clone(types),
+				},
+			})
+		} else {
+			usage = fmt.Sprintf("Generate an %s SDK for use with %s", sdkTypeKeys[types[0]], g.DisplayName())
+		}
+
+		language := g.Language()
+		commands = append(commands, &cli.Command{
+			Name:  language,
+			Usage: usage,
+			Action: func(c *cli.Context) error {
+				return handleCliGenerate(c, language)
+			},
+			Flags: flags,
+		})
 	}
+	return commands
 }
 
 func beforeAction(cCtx *cli.Context) error {
 	if cCtx.String("log-level") == LOG_DEBUG {
 		log.SetLevel(log.DebugLevel)
 	}
+	offline = cCtx.Bool("offline")
+	configFlag = cCtx.String("config")
 	return nil
 }
 
@@ -242,54 +307,157 @@ func handleCliInit(*cli.Context) error {
 func handleCliRun(cCtx *cli.Context) error {
 	LoadConfig()
 
-	var downloader Downloader
-	// for now we only support NeoExpress
-	downloader = NewNeoExpressDownloader(cfg.Tools.NeoExpress.ConfigPath)
+	if cCtx.Bool("with-deps") {
+		if err := addContractDependencies(); err != nil {
+			return err
+		}
+	}
 
-	for _, c := range cfg.Contracts {
-		log.Infof("Processing contract '%s' (%s)", c.Label, c.ScriptHash.StringLE())
-		hosts := cfg.getHosts(*c.SourceNetwork)
-
-		if *c.Download {
-			downloadSuccess := false
-			for _, host := range hosts {
-				log.Debugf("Attempting to download contract '%s' (%s) using NEOXP from network %s", c.Label, c.ScriptHash.StringLE(), host)
-				message, err := downloader.downloadContract(c.ScriptHash, host)
-				if err == nil {
-					log.Info(message)
-					downloadSuccess = true
-					break
+	downloader := NewDownloader(cfg.Defaults.ContractDestination, cfg.resolvePath(cfg.Tools.NeoExpress.ConfigPath))
+
+	frozen := cCtx.Bool("frozen")
+	lock, err := loadLockFile(lockFilePath())
+	if err != nil {
+		return err
+	}
+	if frozen && len(lock.Contracts) == 0 {
+		return fmt.Errorf("--frozen requires %s, run 'cpm run' without --frozen first", lockFilePath())
+	}
+
+	jobs := cCtx.Int("jobs")
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	total := len(cfg.Contracts)
+	results := make([]error, total)
+	queue := make(chan int)
+	var done atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				c := cfg.Contracts[i]
+				if appCtx.Err() != nil {
+					results[i] = appCtx.Err()
+					continue
+				}
+				results[i] = processContract(&c, downloader, lock, frozen)
+				n := done.Add(1)
+				if results[i] != nil {
+					log.Errorf("[%d/%d] %v", n, total, results[i])
+				} else {
+					log.Infof("[%d/%d] Finished contract '%s' (%s)", n, total, c.Label, c.ScriptHash.StringLE())
 				}
-				// just log the error we got from the downloader and try the next host
-				log.Debug(message)
 			}
+		}()
+	}
+	for i := range cfg.Contracts {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
 
-			if !downloadSuccess {
-				log.Fatalf("Failed to download contract '%s' (%s). Use '--log-level DEBUG' for more information", c.Label, c.ScriptHash.StringLE())
-			}
-		} else {
-			log.Debugf("Skipping contract download")
+	if appCtx.Err() != nil {
+		log.Warn("Interrupted, contracts that were not processed completely are not recorded in the lock file")
+	}
+
+	var failed []string
+	for i, err := range results {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("'%s' (%s)", cfg.Contracts[i].Label, cfg.Contracts[i].ScriptHash.StringLE()))
 		}
+	}
+	log.Infof("Processed %d contracts: %d succeeded, %d failed", total, total-len(failed), len(failed))
 
-		if *c.GenerateSdk {
-			generateSuccess := false
-			for _, host := range hosts {
-				err := fetchManifestAndGenerateSDK(&c, host)
-				if err == nil {
-					generateSuccess = true
-					break
-				}
-				log.Debug(err)
-			}
+	if !frozen {
+		lock.retain(cfg.Contracts)
+		if err := lock.saveToDisk(lockFilePath()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", lockFilePath(), err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to process contracts %s. Use '--log-level DEBUG' for more information", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// processContract downloads the contract and generates its SDKs as configured and records the source state in the lock
+// file. If frozen is set it fails when the source state no longer matches the lock file instead. It is safe to call
+// concurrently for different contracts
+func processContract(c *ContractConfig, downloader Downloader, lock *LockFile, frozen bool) error {
+	log.Infof("Processing contract '%s' (%s)", c.Label, c.ScriptHash.StringLE())
+	hosts, err := cfg.getHosts(*c.SourceNetwork)
+	if err != nil {
+		return fmt.Errorf("failed to process contract '%s' (%s): %w", c.Label, c.ScriptHash.StringLE(), err)
+	}
 
-			if !generateSuccess {
-				log.Fatalf("Failed to generate SDK for contract '%s' (%s). Use '--log-level DEBUG' for more information", c.Label, c.ScriptHash.StringLE())
+	if !*c.Download && !*c.GenerateSdk {
+		log.Debugf("Skipping contract download and SDK generation")
+		return nil
+	}
+
+	height := c.SourceHeight
+	var locked *LockEntry
+	if frozen {
+		locked = lock.get(c.ScriptHash)
+		if locked == nil {
+			return fmt.Errorf("contract '%s' (%s) is not in %s", c.Label, c.ScriptHash.StringLE(), lockFilePath())
+		}
+		if height != nil && *height != locked.BlockHeight {
+			return fmt.Errorf("contract '%s' (%s) does not match %s: source height changed from %d to %d", c.Label,
+				c.ScriptHash.StringLE(), lockFilePath(), locked.BlockHeight, *height)
+		}
+		height = &locked.BlockHeight
+	}
+
+	// the lock entry, the downloaded contract and the SDKs are all made from this state. The native downloaders take it
+	// from the cache, neoxp fetches the state at its height itself
+	_, fetchesItself := downloader.(*NeoExpressDownloader)
+	contract, err := fetchSourceState(c.ScriptHash, hosts, height, *c.Download && !fetchesItself)
+	if err != nil {
+		return fmt.Errorf("failed to fetch state of contract '%s' (%s): %w", c.Label, c.ScriptHash.StringLE(), err)
+	}
+	entry, err := newLockEntry(c, contract)
+	if err != nil {
+		return err
+	}
+
+	if frozen {
+		if err := locked.verify(entry); err != nil {
+			return fmt.Errorf("contract '%s' (%s) does not match %s: %w", c.Label, c.ScriptHash.StringLE(), lockFilePath(), err)
+		}
+	}
+
+	if *c.Download {
+		err := tryHosts(hosts, log.WarnLevel, func(host string) error {
+			log.Debugf("Attempting to download contract '%s' (%s) from network %s", c.Label, c.ScriptHash.StringLE(), host)
+			message, err := downloader.downloadContract(c.ScriptHash, host, &contract.Height)
+			if err != nil {
+				return &downloadError{message: message, err: err}
 			}
-		} else {
-			log.Debugf("Skipping SDK generation")
+			log.Info(message)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to download contract '%s' (%s)", c.Label, c.ScriptHash.StringLE())
 		}
+	} else {
+		log.Debugf("Skipping contract download")
 	}
 
+	if *c.GenerateSdk {
+		if err := generateContractSDKs(c, &contract.State.Manifest); err != nil {
+			return fmt.Errorf("failed to generate SDK for contract '%s' (%s): %w", c.Label, c.ScriptHash.StringLE(), err)
+		}
+	} else {
+		log.Debugf("Skipping SDK generation")
+	}
+
+	lock.set(*entry)
 	return nil
 }
 
@@ -298,17 +466,65 @@ func handleCliDownloadContract(cCtx *cli.Context) error {
 	networkHost := cCtx.String("N")
 	contractHash := cCtx.String("c")
 	configPath := cCtx.String("i")
+	destination := cCtx.String("d")
 	saveContract := cCtx.Bool("s")
 
+	var height *uint32
+	if cCtx.IsSet("height") {
+		h := cCtx.Uint64("height")
+		if h > math.MaxUint32 {
+			return fmt.Errorf("invalid height %d", h)
+		}
+		height = new(uint32)
+		*height = uint32(h)
+	}
+
 	LoadConfig()
 	hosts, err := getHosts(networkLabel, networkHost)
 	if err != nil {
 		return err
 	}
 
-	// for now, we only support NeoExpress
-	downloader := NewNeoExpressDownloader(configPath)
-	return downloadContract(hosts, contractHash, downloader, saveContract, false)
+	contractHash, err = resolveContractFlag(contractHash, networkLabel, hosts)
+	if err != nil {
+		return err
+	}
+
+	if configPath == "" {
+		configPath = cfg.resolvePath(cfg.Tools.NeoExpress.ConfigPath)
+	}
+	if destination == "" {
+		destination = cfg.Defaults.ContractDestination
+	}
+	downloader := NewDownloader(destination, configPath)
+	err = downloadContract(hosts, contractHash, height, downloader, saveContract, false)
+	if err != nil || !cCtx.Bool("with-deps") {
+		return err
+	}
+
+	scriptHash, _ := util.Uint160DecodeStringLE(strings.TrimPrefix(contractHash, "0x"))
+	deps, err := resolveDependencies([]util.Uint160{scriptHash}, hosts, height)
+	if err != nil {
+		return err
+	}
+
+	var sourceNetwork *string
+	if networkLabel != "" {
+		sourceNetwork = &networkLabel
+	}
+	for _, d := range deps {
+		err = downloadContract(hosts, d.ScriptHash.StringLE(), height, downloader, false, false)
+		if err != nil {
+			return err
+		}
+		if saveContract {
+			cfg.addDependency(d.Label, d.ScriptHash, sourceNetwork, height)
+		}
+	}
+	if saveContract && len(deps) > 0 {
+		cfg.saveToDisk()
+	}
+	return nil
 }
 
 func handleCliDownloadManifest(cCtx *cli.Context) error {
@@ -328,9 +544,33 @@ func handleCliDownloadManifest(cCtx *cli.Context) error {
 			return err
 		}
 	}
+
+	contractHash, err = resolveContractFlag(contractHash, networkLabel, hosts)
+	if err != nil {
+		return err
+	}
 	return downloadManifest(hosts, contractHash, saveContract, false)
 }
 
+// resolveContractFlag resolves the value of the '-c' flag, which can be a script hash, native contract name or NNS
+// domain, to a script hash
+func resolveContractFlag(ref, networkLabel string, hosts []string) (string, error) {
+	nnsHash := MainNetNNSHash
+	if networkLabel != "" {
+		network, err := cfg.getNetwork(networkLabel)
+		if err != nil {
+			return "", err
+		}
+		nnsHash = network.nnsHash()
+	}
+
+	scriptHash, err := resolveScriptHash(ref, hosts, nnsHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert script hash: %v", err)
+	}
+	return scriptHash.StringLE(), nil
+}
+
 func handleCliGenerate(cCtx *cli.Context, language string) error {
 	m, _, err := readManifest(cCtx.String("m"))
 	if err != nil {
@@ -339,18 +579,15 @@ func handleCliGenerate(cCtx *cli.Context, language string) error {
 
 	sdkType := cCtx.String("t")
 	if sdkType == "" {
-		if language == LANG_TYPESCRIPT {
-			sdkType = generators.SDKOffChain
-		} else {
-			sdkType = generators.SDKOnChain
-		}
+		sdkType = generators.Get(language).SDKTypes()[0]
 	}
 
-	dest := cCtx.String("o")
-	if dest == "" {
-		dest = cfg.getSdkDestination(language, sdkType)
-	} else {
-		dest = EnsureSuffix(dest)
+	dest := cfg.getSdkDestination(cCtx.String("o"), nil, language, sdkType)
+	templateDir := cfg.getTemplateDir(cCtx.String("templates"), nil, language, sdkType)
+
+	bindings, err := readBindings(cCtx.String("b"))
+	if err != nil {
+		log.Fatalf("can't read binding config: %s", err)
 	}
 
 	scriptHash := util.Uint160{}
@@ -361,7 +598,7 @@ func handleCliGenerate(cCtx *cli.Context, language string) error {
 			log.Fatalf("failed to convert script hash: %v", err)
 		}
 	}
-	return generateSDK(&generators.GenerateCfg{Manifest: m, ContractHash: scriptHash, SdkDestination: dest}, language, sdkType)
+	return generateSDK(&generators.GenerateCfg{Manifest: m, ContractHash: scriptHash, SdkDestination: dest, TemplateDir: templateDir, Bindings: bindings}, language, sdkType)
 }
 
 func handleCliVersion(cCtx *cli.Context) error {
@@ -370,33 +607,28 @@ func handleCliVersion(cCtx *cli.Context) error {
 }
 
 // 'cfg' is expected to be initialized
-func downloadContract(hosts []string, contractHash string, downloader Downloader, saveContract, testing bool) error {
+func downloadContract(hosts []string, contractHash string, height *uint32, downloader Downloader, saveContract, testing bool) error {
 	scriptHash, err := util.Uint160DecodeStringLE(strings.TrimPrefix(contractHash, "0x"))
 	if err != nil {
 		return fmt.Errorf("failed to convert script hash: %v", err)
 	}
 
 	if saveContract {
-		cfg.addContract("unknown", scriptHash)
+		cfg.addContract("unknown", scriptHash, height)
 		if !testing {
 			cfg.saveToDisk()
 		}
 	}
 
-	success := false
-	for _, host := range hosts {
-		message, err := downloader.downloadContract(scriptHash, host)
+	err = tryHosts(hosts, log.WarnLevel, func(host string) error {
+		message, err := downloader.downloadContract(scriptHash, host, height)
 		if err != nil {
-			// just log the error we got from the downloader and try the next host
-			log.Debug(message)
-		} else {
-			log.Info(message)
-			success = true
-			break
+			return &downloadError{message: message, err: err}
 		}
-	}
-
-	if !success {
+		log.Info(message)
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to download contract %s. Use '--log-level DEBUG' for more information", scriptHash)
 	}
 	return nil
@@ -408,77 +640,101 @@ func downloadManifest(hosts []string, contractHash string, saveContract, testing
 		return fmt.Errorf("failed to convert script hash: %v", err)
 	}
 
-	for _, host := range hosts {
-		m, err := fetchManifest(&scriptHash, host)
-		if err != nil {
-			log.Debug(err)
-			continue
-		} else {
-			f, err := os.Create("contract.manifest.json")
-			if err != nil {
-				return err
-			}
+	var m *manifest.Manifest
+	err = tryHosts(hosts, log.WarnLevel, func(host string) error {
+		m, err = fetchManifest(&scriptHash, host, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch manifest. Use '--log-level DEBUG' for more information")
+	}
 
-			out, err := json.MarshalIndent(m, "", "   ")
-			if err != nil {
-				return err
-			}
+	f, err := os.Create("contract.manifest.json")
+	if err != nil {
+		return err
+	}
 
-			_, err = f.Write(out)
-			if err != nil {
-				return err
-			}
-			log.Info("Written manifest to contract.manifest.json")
+	out, err := json.MarshalIndent(m, "", "   ")
+	if err != nil {
+		return err
+	}
 
-			if saveContract {
-				cfg.addContract(m.Name, scriptHash)
-				if !testing {
-					cfg.saveToDisk()
-				}
-			}
+	_, err = f.Write(out)
+	if err != nil {
+		return err
+	}
+	log.Info("Written manifest to contract.manifest.json")
 
-			return nil
+	if saveContract {
+		cfg.addContract(m.Name, scriptHash, nil)
+		if !testing {
+			cfg.saveToDisk()
 		}
 	}
-	return fmt.Errorf("failed to fetch manifest. Use '--log-level DEBUG' for more information")
+	return nil
 }
 
-// must fetch and generate an SDK. Must return an error if generation failed or nothing is generated
-func fetchManifestAndGenerateSDK(c *ContractConfig, host string) error {
-	m, err := fetchManifest(&c.ScriptHash, host)
-	if err != nil {
-		return err
+// addContractDependencies resolves the dependencies of all contracts that are downloaded and adds the missing ones to
+// the config as auto-added contracts. 'cfg' is expected to be initialized
+func addContractDependencies() error {
+	added := false
+	for _, c := range cfg.Contracts {
+		if !*c.Download {
+			continue
+		}
+
+		hosts, err := cfg.getHosts(*c.SourceNetwork)
+		if err != nil {
+			return err
+		}
+		deps, err := resolveDependencies([]util.Uint160{c.ScriptHash}, hosts, c.SourceHeight)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deps {
+			sourceNetwork := *c.SourceNetwork
+			if cfg.addDependency(d.Label, d.ScriptHash, &sourceNetwork, c.SourceHeight) {
+				log.Infof("Added dependency '%s' (%s) to %s", d.Label, d.ScriptHash.StringLE(), cfg.path)
+				added = true
+			}
+		}
 	}
 
-	var onChainLanguages []string = nil
-	if c.OnChain != nil {
-		onChainLanguages = c.OnChain.Languages
-	} else if cfg.Defaults.OnChain != nil {
-		onChainLanguages = cfg.Defaults.OnChain.Languages
+	if added {
+		cfg.saveToDisk()
+		cfg.applyContractDefaults()
 	}
+	return nil
+}
 
-	if onChainLanguages != nil {
-		for _, l := range onChainLanguages {
-			err = generateSDK(&generators.GenerateCfg{Manifest: m, ContractHash: c.ScriptHash, SdkDestination: cfg.getSdkDestination(l, generators.SDKOnChain)}, l, generators.SDKOnChain)
-			if err != nil {
-				return err
-			}
+// must fetch and generate an SDK. Must return an error if generation failed or nothing is generated
+// generateContractSDKs generates the SDKs of all languages configured for the contract from its manifest
+func generateContractSDKs(c *ContractConfig, m *manifest.Manifest) error {
+	var (
+		bindings *binding.Config
+		err      error
+	)
+	if c.Bindings != nil {
+		bindings, err = readBindings(cfg.resolvePath(*c.Bindings))
+		if err != nil {
+			return fmt.Errorf("can't read binding config of contract '%s': %w", c.Label, err)
 		}
 	}
 
-	var offChainLanguages []string = nil
-	if c.OffChain != nil {
-		offChainLanguages = c.OffChain.Languages
-	} else if cfg.Defaults.OffChain != nil {
-		offChainLanguages = cfg.Defaults.OffChain.Languages
+	onChainLanguages := c.languages(generators.SDKOnChain)
+	for _, l := range onChainLanguages {
+		err = generateSDK(c.generateCfg(m, bindings, l, generators.SDKOnChain), l, generators.SDKOnChain)
+		if err != nil {
+			return err
+		}
 	}
 
-	if offChainLanguages != nil {
-		for _, l := range offChainLanguages {
-			err = generateSDK(&generators.GenerateCfg{Manifest: m, ContractHash: c.ScriptHash, SdkDestination: cfg.getSdkDestination(l, generators.SDKOffChain)}, l, generators.SDKOffChain)
-			if err != nil {
-				return err
-			}
+	offChainLanguages := c.languages(generators.SDKOffChain)
+	for _, l := range offChainLanguages {
+		err = generateSDK(c.generateCfg(m, bindings, l, generators.SDKOffChain), l, generators.SDKOffChain)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -490,24 +746,49 @@ func fetchManifestAndGenerateSDK(c *ContractConfig, host string) error {
 	return nil
 }
 
-func fetchManifest(scriptHash *util.Uint160, host string) (*manifest.Manifest, error) {
-	opts := rpcclient.Options{}
-	client, err := rpcclient.New(context.TODO(), host, opts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create RPC client: %v", err)
+// languages returns the languages to generate SDKs of the type for, which are the languages of the contract or else
+// the default languages
+func (c *ContractConfig) languages(sdkType string) []string {
+	if gc := generateConfig(c.OnChain, c.OffChain, sdkType); gc != nil {
+		return gc.Languages
 	}
+	if gc := generateConfig(cfg.Defaults.OnChain, cfg.Defaults.OffChain, sdkType); gc != nil {
+		return gc.Languages
+	}
+	return nil
+}
 
-	err = client.Init()
-	if err != nil {
-		return nil, fmt.Errorf("RPCClient init failed with: %v", err)
+// sdkName returns the name of the generated class, package or module, which is the manifest name unless the contract
+// overrides it
+func (c *ContractConfig) sdkName(m *manifest.Manifest) string {
+	if c.SdkName != nil {
+		return *c.SdkName
 	}
+	return m.Name
+}
 
-	log.Debugf("Attempting to fetch manifest for contract '%s' using %s", scriptHash.StringLE(), host)
-	state, err := client.GetContractStateByHash(*scriptHash)
+func (c *ContractConfig) generateCfg(m *manifest.Manifest, bindings *binding.Config, language, sdkType string) *generators.GenerateCfg {
+	gc := &generators.GenerateCfg{
+		Manifest:       m,
+		ContractHash:   c.ScriptHash,
+		SdkDestination: cfg.getSdkDestination("", c, language, sdkType),
+		TemplateDir:    cfg.getTemplateDir("", c, language, sdkType),
+		Bindings:       bindings,
+	}
+	if c.SdkName != nil {
+		gc.ContractName = *c.SdkName
+	}
+	log.Debugf("SDK name of %s %s SDK for contract '%s' is %s", sdkType, language, c.Label, gc.Name())
+	return gc
+}
+
+// fetchManifest returns the manifest of the contract at the given block height, or the latest if height is nil
+func fetchManifest(scriptHash *util.Uint160, host string, height *uint32) (*manifest.Manifest, error) {
+	contract, err := loadContract(*scriptHash, host, height, false, "manifest")
 	if err != nil {
-		return nil, fmt.Errorf("getcontractstate failed with: %v", err)
+		return nil, err
 	}
-	return &state.Manifest, nil
+	return &contract.State.Manifest, nil
 }
 
 func readManifest(filename string) (*manifest.Manifest, []byte, error) {
@@ -528,26 +809,52 @@ func readManifest(filename string) (*manifest.Manifest, []byte, error) {
 	return m, manifestBytes, nil
 }
 
-func generateSDK(cfg *generators.GenerateCfg, language, sdkType string) error {
-	var err error
-	if language == LANG_PYTHON {
-		err = python.GenerateSDK(cfg, sdkType)
-	} else if language == LANG_JAVA {
-		err = java.GenerateSDK(cfg, sdkType)
-	} else if language == LANG_CSHARP {
-		err = csharp.GenerateCsharpSDK(cfg)
-	} else if language == LANG_GO {
-		err = golang.GenerateSDK(cfg, sdkType)
-	} else if language == LANG_TYPESCRIPT {
-		err = typescript.GenerateTypeScriptSDK(cfg)
-	} else {
-		log.Fatalf("language '%s' is unsupported", language)
+// readBindings reads a neo-go binding config like 'neo-go contract generate-wrapper --config' does. It returns nil if
+// filename is empty
+func readBindings(filename string) (*binding.Config, error) {
+	if filename == "" {
+		return nil, nil
 	}
 
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	bindings := binding.NewConfig()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&bindings); err != nil {
+		return nil, fmt.Errorf("can't parse %s: %w", filename, err)
+	}
+	return &bindings, nil
+}
+
+// sdkDestinationLocks ensures that SDKs are not generated into the same destination concurrently
+var sdkDestinationLocks sync.Map
+
+func generateSDK(cfg *generators.GenerateCfg, language, sdkType string) error {
+	lock, _ := sdkDestinationLocks.LoadOrStore(cfg.SdkDestination, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	g := generators.Get(language)
+	if g == nil {
+		return fmt.Errorf("language '%s' is unsupported", language)
+	}
+	if cfg.Context == nil {
+		cfg.Context = appCtx
+	}
+	return g.Generate(cfg, sdkType)
+}
+
+// sdkOutputPath returns the file or directory the SDK of the contract is generated at
+func sdkOutputPath(language, sdkType, destination, contractName string) string {
+	g := generators.Get(language)
+	if g == nil {
+		return ""
+	}
+	return g.OutputPath(destination, contractName, sdkType)
 }
 
 func getHosts(networkLabel, networkHost string) ([]string, error) {
@@ -555,14 +862,11 @@ func getHosts(networkLabel, networkHost string) ([]string, error) {
 		return nil, fmt.Errorf("-n and -N flags are mutually exclusive")
 	}
 
-	var hosts []string
 	if len(networkLabel) > 0 {
-		hosts = cfg.getHosts(networkLabel)
+		return cfg.getHosts(networkLabel)
 	} else if len(networkHost) > 0 {
 		// TODO: sanity check value
-		hosts = []string{networkHost}
-	} else {
-		return nil, fmt.Errorf("must specify either -n or -N flag")
+		return []string{networkHost}, nil
 	}
-	return hosts, nil
+	return nil, fmt.Errorf("must specify either -n or -N flag")
 }
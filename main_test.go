@@ -8,15 +8,306 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
+	"cpm/generators"
+
+	"github.com/gorilla/websocket"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
 	"github.com/nspcc-dev/neo-go/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func Test_GenerateSDK(t *testing.T) {
+	assert.Equal(t, []string{"csharp", "go", "java", "python"}, generators.Languages(generators.SDKOnChain))
+	assert.Equal(t, []string{"go", "java", "python", "ts"}, generators.Languages(generators.SDKOffChain))
+
+	dir := t.TempDir()
+	m := manifest.NewManifest("Sample Contract")
+	m.ABI.Methods = []manifest.Method{{
+		Name:       "balanceOf",
+		Parameters: []manifest.Parameter{{Name: "account", Type: smartcontract.Hash160Type}},
+		ReturnType: smartcontract.IntegerType,
+		Safe:       true,
+	}}
+	manifestPath := writeTestManifest(t, dir, m)
+
+	for _, g := range generators.All() {
+		for _, sdkType := range g.SDKTypes() {
+			t.Run(g.Language()+" "+sdkType, func(t *testing.T) {
+				out := filepath.Join(dir, sdkType, g.Language())
+				args := []string{"cpm", "generate", g.Language(), "-m", manifestPath, "-o", out}
+				if len(g.SDKTypes()) > 1 {
+					args = append(args, "-t", sdkType)
+				}
+				require.NoError(t, newApp().Run(args))
+				_, err := os.Stat(sdkOutputPath(g.Language(), sdkType, EnsureSuffix(out), m.Name))
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
+
+func Test_GeneratorPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	bin := t.TempDir()
+	writePlugin := func(response string) {
+		script := "#!/bin/sh\ncat > \"$(dirname \"$0\")/request.json\"\necho '" + response + "'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(bin, "cpm-gen-kotlin"), []byte(script), 0755))
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Cleanup(func() { generators.Unregister("kotlin") })
+
+	dir := t.TempDir()
+	m := manifest.NewManifest("Sample Contract")
+	m.ABI.Methods = []manifest.Method{{Name: "symbol", ReturnType: smartcontract.StringType, Safe: true}}
+	manifestPath := writeTestManifest(t, dir, m)
+	out := filepath.Join(dir, "out")
+	args := []string{"cpm", "generate", "kotlin", "-m", manifestPath, "-t", generators.SDKOffChain, "-o", out,
+		"-c", "0x76a8f8a7a901b29a33013b469949f4b08db15756"}
+
+	t.Run("should write the files of the plugin", func(t *testing.T) {
+		writePlugin(`{"files":[{"path":"src/Sample.kt","content":"class Sample"}]}`)
+		require.NoError(t, newApp().Run(args))
+
+		content, err := os.ReadFile(filepath.Join(out, "SampleContract", "src", "Sample.kt"))
+		require.NoError(t, err)
+		assert.Equal(t, "class Sample", string(content))
+
+		var request generators.PluginRequest
+		data, err := os.ReadFile(filepath.Join(bin, "request.json"))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &request))
+		assert.Equal(t, "SampleContract", request.Contract.ContractName)
+		assert.Equal(t, "symbol", request.Contract.Methods[0].Name)
+		assert.Equal(t, "String", request.Contract.Methods[0].ReturnType)
+		assert.Equal(t, "0x76a8f8a7a901b29a33013b469949f4b08db15756", request.ContractHash)
+		assert.Equal(t, generators.SDKOffChain, request.SdkType)
+		var requestManifest manifest.Manifest
+		require.NoError(t, json.Unmarshal(request.Manifest, &requestManifest))
+		assert.Equal(t, m.Name, requestManifest.Name)
+	})
+
+	t.Run("should report plugin errors", func(t *testing.T) {
+		writePlugin(`{"error":"on-chain SDKs are not supported"}`)
+		err := newApp().Run(args)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "on-chain SDKs are not supported")
+	})
+
+	t.Run("should not write files outside of the SDK directory", func(t *testing.T) {
+		writePlugin(`{"files":[{"path":"../../escape.kt","content":""}]}`)
+		err := newApp().Run(args)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "outside of the SDK directory")
+		assert.NoFileExists(t, filepath.Join(dir, "escape.kt"))
+	})
+
+	t.Run("should ignore plugins in $PATH that are not executable", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(bin, "cpm-gen-rust"), []byte("#!/bin/sh\n"), 0644))
+		newApp()
+		assert.Nil(t, generators.Get("rust"))
+	})
+
+	t.Run("should use plugins configured in cpm.yaml", func(t *testing.T) {
+		t.Cleanup(func() { generators.Unregister("scala") })
+		tools := filepath.Join(dir, "tools")
+		require.NoError(t, os.MkdirAll(tools, 0755))
+		script := "#!/bin/sh\necho '{\"files\":[{\"path\":\"Sample.scala\",\"content\":\"object Sample\"}]}'\n"
+		require.NoError(t, os.WriteFile(filepath.Join(tools, "gen-scala"), []byte(script), 0755))
+		configPath := filepath.Join(dir, DEFAULT_CONFIG_FILE)
+		require.NoError(t, os.WriteFile(configPath, []byte("tools:\n  generators:\n    scala: tools/gen-scala\n"), 0644))
+
+		scalaArgs := []string{"cpm", "--config", configPath, "generate", "scala", "-m", manifestPath, "-t", generators.SDKOffChain, "-o", out}
+		require.NoError(t, newApp().Run(scalaArgs))
+		assert.FileExists(t, filepath.Join(out, "SampleContract", "Sample.scala"))
+	})
+}
+
+func Test_GenerateSDKWithBindings(t *testing.T) {
+	dir := t.TempDir()
+	m := manifest.NewManifest("Geometry")
+	m.ABI.Methods = []manifest.Method{{
+		Name:       "getPoint",
+		Parameters: []manifest.Parameter{{Name: "id", Type: smartcontract.IntegerType}},
+		ReturnType: smartcontract.ArrayType,
+		Safe:       true,
+	}, {
+		Name:       "setPoints",
+		Parameters: []manifest.Parameter{{Name: "points", Type: smartcontract.ArrayType}},
+		ReturnType: smartcontract.VoidType,
+	}}
+	bindingsPath := filepath.Join(dir, "bindings.yml")
+	require.NoError(t, os.WriteFile(bindingsPath, []byte(`
+namedtypes:
+  lib.Point:
+    base: Array
+    name: lib.Point
+    fields:
+      - field: x
+        base: Integer
+      - field: tags
+        base: Array
+        value:
+          base: String
+types:
+  getPoint:
+    base: Array
+    name: lib.Point
+  setPoints.points:
+    base: Array
+    value:
+      base: Array
+      name: lib.Point
+`), 0644))
+
+	tests := []sdkTest{
+		{"csharp", generators.SDKOnChain, "", []string{"public class LibPoint {", "public string[] Tags;",
+			"public static LibPoint GetPoint(BigInteger id)", "SetPoints(LibPoint[] points)"}},
+		{"go", generators.SDKOffChain, "", []string{"type LibPoint struct", "GetPoint(id *big.Int) (*LibPoint, error)"}},
+		{"java", generators.SDKOnChain, "", []string{"public static class LibPoint {", "public List<String> tags;",
+			"public native LibPoint getPoint(int id);", "setPoints(List<LibPoint> points)"}},
+		{"java", generators.SDKOffChain, "", []string{"public static class LibPoint {",
+			"return LibPoint.fromStackItem(response.getInvocationResult().getFirstStackItem());",
+			"ContractParameter.array(points.stream().map(item0 -> item0.toContractParameter()).collect(Collectors.toList()))"}},
+		{"python", generators.SDKOnChain, "contract.py", []string{"class LibPoint:", "tags: list[str]",
+			"def get_point(id: int) -> LibPoint:", "def set_points(points: list[LibPoint])"}},
+		{"python", generators.SDKOffChain, "contract.py", []string{"@dataclass\nclass LibPoint:",
+			"[item0.as_str() for item0 in fields[1].as_list()]", "lambda res: LibPoint.from_stack_item(unwrap.item(res))",
+			"[[item0.to_list() for item0 in points]]"}},
+		{"ts", generators.SDKOffChain, "api.ts", []string{"export class LibPoint {",
+			"params: { points: LibPoint[] }", "{ type: 'Array', value: params.points.map((item0: any) => item0.toArgument(parser)) }",
+			"[parser.formatRpcArgument(this.x, { type: 'Integer' }), { type: 'Array', value: this.tags.map(",
+			"new LibPoint(parser.parseRpcResponse(values[0], { type: 'Integer' }), values[1].value.map("}},
+		{"ts", generators.SDKOffChain, "Geometry.ts", []string{"import { LibPoint } from './api'",
+			"Promise<LibPoint>", "return LibPoint.fromArray(res.stack[0].value, this.config.parser)"}},
+	}
+	runSDKTests(t, m, tests, "-b", bindingsPath)
+}
+
+func Test_GenerateSDKEvents(t *testing.T) {
+	m := manifest.NewManifest("Token")
+	m.ABI.Methods = []manifest.Method{{Name: "symbol", ReturnType: smartcontract.StringType, Safe: true}}
+	m.ABI.Events = []manifest.Event{{
+		Name: "Transfer",
+		Parameters: []manifest.Parameter{
+			{Name: "from", Type: smartcontract.Hash160Type},
+			{Name: "to", Type: smartcontract.Hash160Type},
+			{Name: "amount", Type: smartcontract.IntegerType},
+		},
+	}, {
+		Name: "Paused",
+	}, {
+		Name: "transfer_done",
+	}}
+	var large []manifest.Parameter
+	for i := range 17 {
+		large = append(large, manifest.Parameter{Name: fmt.Sprintf("arg%d", i), Type: smartcontract.IntegerType})
+	}
+	m.ABI.Events = append(m.ABI.Events, manifest.Event{Name: "Large", Parameters: large})
+
+	tests := []sdkTest{
+		{"csharp", generators.SDKOnChain, "", []string{
+			"public delegate void OnTransferDelegate(UInt160 from, UInt160 to, BigInteger amount);",
+			"[DisplayName(\"Transfer\")]\n        public static event OnTransferDelegate OnTransfer;"}},
+		{"java", generators.SDKOnChain, "", []string{
+			"@DisplayName(\"Transfer\")\n    public static Event3Args<Hash160, Hash160, Integer> onTransfer;",
+			"@DisplayName(\"Paused\")\n    public static Event0Args onPaused;",
+			"@DisplayName(\"transfer_done\")\n    public static Event0Args onTransferDone;"}},
+		{"java", generators.SDKOffChain, "", []string{"public static class TransferEvent {",
+			"public static TransferEvent fromNotification(Notification notification) {",
+			"Hash160.fromAddress(state.get(0).getAddress()),",
+			"public List<TransferEvent> getTransferEvents(NeoApplicationLog applicationLog) {",
+			"notification.getEventName().equals(\"Transfer\")"}},
+		{"python", generators.SDKOnChain, "contract.py", []string{"on_transfer = CreateNewEvent(",
+			"('from', UInt160),", "'Transfer'\n)"}},
+		{"python", generators.SDKOffChain, "contract.py", []string{"@dataclass\nclass TransferEvent:",
+			"from_: types.UInt160 | NeoAddress", "state[2].as_int(),",
+			"def transfer_events(self, log: noderpc.TransactionApplicationLog) -> list[TransferEvent]:",
+			"notification.event_name == \"Transfer\""}},
+		{"ts", generators.SDKOffChain, "Token.ts", []string{"listenTransferEvent(callback", "listenTransfer_doneEvent(callback",
+			"addEventListener(this.config.scriptHash, 'transfer_done', callback)"}},
+	}
+	runSDKTests(t, m, tests)
+
+	t.Run("should warn about events neow3j can't declare", func(t *testing.T) {
+		var output strings.Builder
+		log.SetOutput(&output)
+		t.Cleanup(func() { log.SetOutput(os.Stdout) })
+
+		dir := t.TempDir()
+		args := []string{"cpm", "generate", "java", "-m", writeTestManifest(t, dir, m), "-o", dir, "-t", generators.SDKOnChain}
+		require.NoError(t, newApp().Run(args))
+		assert.Contains(t, output.String(), "Skipping event 'Large' of contract 'Token', neow3j events can't have more than 16 arguments")
+
+		content, err := os.ReadFile(sdkOutputPath("java", generators.SDKOnChain, EnsureSuffix(dir), m.Name))
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "onLarge")
+	})
+}
+
+func Test_GeneratePythonKeywords(t *testing.T) {
+	m := manifest.NewManifest("Token")
+	m.ABI.Methods = []manifest.Method{{
+		Name:       "transfer",
+		Parameters: []manifest.Parameter{{Name: "from", Type: smartcontract.Hash160Type}, {Name: "to", Type: smartcontract.Hash160Type}},
+		ReturnType: smartcontract.BoolType,
+	}}
+
+	runSDKTests(t, m, []sdkTest{
+		{"python", generators.SDKOnChain, "contract.py", []string{"def transfer(from_: UInt160, to: UInt160) -> bool:"}},
+		{"python", generators.SDKOffChain, "contract.py", []string{", from_: types.UInt160 | NeoAddress", "_check_address_and_convert(from_)"}},
+	})
+}
+
+// sdkTest is a case of the table driven SDK tests. want are snippets the generated file must contain
+type sdkTest struct {
+	language, sdkType, file string
+	want                    []string
+}
+
+// writeTestManifest writes the manifest to the directory and returns its path
+func writeTestManifest(t *testing.T, dir string, m *manifest.Manifest) string {
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	manifestPath := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(manifestPath, data, 0644))
+	return manifestPath
+}
+
+// runSDKTests generates the SDK of every test from the manifest with the additional generate flags and checks the
+// content of the generated file
+func runSDKTests(t *testing.T, m *manifest.Manifest, tests []sdkTest, flags ...string) {
+	dir := t.TempDir()
+	manifestPath := writeTestManifest(t, dir, m)
+	for _, tt := range tests {
+		t.Run(tt.language+" "+tt.sdkType+" "+tt.file, func(t *testing.T) {
+			out := filepath.Join(dir, tt.sdkType, tt.language)
+			args := append([]string{"cpm", "generate", tt.language, "-m", manifestPath, "-o", out}, flags...)
+			if len(generators.Get(tt.language).SDKTypes()) > 1 {
+				args = append(args, "-t", tt.sdkType)
+			}
+			require.NoError(t, newApp().Run(args))
+
+			content, err := os.ReadFile(filepath.Join(sdkOutputPath(tt.language, tt.sdkType, EnsureSuffix(out), m.Name), tt.file))
+			require.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, string(content), want)
+			}
+
+		})
+	}
+}
+
 func Test_DownloadContract(t *testing.T) {
 	log.SetLevel(log.WarnLevel)
 	t.Cleanup(func() { log.SetOutput(os.Stderr) })
@@ -25,7 +316,7 @@ func Test_DownloadContract(t *testing.T) {
 	h := []string{"127.0.0.1:10333"}
 
 	t.Run("invalid contract hash should fail", func(t *testing.T) {
-		err := downloadContract(nil, "invalidhash", nil, false, true)
+		err := downloadContract(nil, "invalidhash", nil, nil, false, true)
 		require.Error(t, err)
 
 		expected := "failed to convert script hash"
@@ -33,7 +324,7 @@ func Test_DownloadContract(t *testing.T) {
 	})
 
 	t.Run("should download and save contract to config", func(t *testing.T) {
-		err := downloadContract(h, c.StringLE(), NewOkDownloader(), true, true)
+		err := downloadContract(h, c.StringLE(), nil, NewOkDownloader(), true, true)
 		require.NoError(t, err)
 
 		// test if contract is added
@@ -54,7 +345,7 @@ func Test_DownloadContract(t *testing.T) {
 
 		downloader := NewMockDownloader([]bool{false, true})
 
-		err := downloadContract(hosts, c.StringLE(), &downloader, false, true)
+		err := downloadContract(hosts, c.StringLE(), nil, &downloader, false, true)
 		require.NoErrorf(t, err, "expected download to succeed for %s", successHost)
 
 		if assert.Greater(t, logs.Len(), 1) {
@@ -67,7 +358,7 @@ func Test_DownloadContract(t *testing.T) {
 		logs := NewMockLogs(t)
 		downloader := NewMockDownloader([]bool{false})
 
-		err := downloadContract(h, c.StringLE(), &downloader, false, true)
+		err := downloadContract(h, c.StringLE(), nil, &downloader, false, true)
 		require.Error(t, err)
 		if assert.Equal(t, logs.Len(), 1) {
 			assert.Contains(t, logs.lines[0], downloader.responseMsg[0])
@@ -75,6 +366,59 @@ func Test_DownloadContract(t *testing.T) {
 	})
 }
 
+func Test_ProcessContract(t *testing.T) {
+	t.Run("should return an error for an unknown source network", func(t *testing.T) {
+		network := "unknown"
+		c := ContractConfig{Label: "A", SourceNetwork: &network}
+		err := processContract(&c, NewOkDownloader(), &LockFile{}, false)
+		assert.ErrorContains(t, err, "could not find hosts for label: unknown")
+	})
+
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	networks := cfg.Networks
+	t.Cleanup(func() { cfg.Networks = networks })
+	network := "priv"
+	download, generateSdk := true, false
+	emptyStorage := `{"jsonrpc":"2.0","id":1,"result":{"results":[],"next":0,"truncated":false}}`
+
+	t.Run("should download the state the lock entry is made from", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}, {"findstorage", emptyStorage}})
+		defer srv.Close()
+		cfg.Networks = []NetworkConfig{{Label: network, Hosts: []string{srv.URL}}}
+
+		c := ContractConfig{Label: "A", SourceNetwork: &network, Download: &download, GenerateSdk: &generateSdk}
+		downloader := &MockDownloader{responses: []bool{true}}
+		lock := &LockFile{}
+		require.NoError(t, processContract(&c, downloader, lock, false))
+
+		entry := lock.get(c.ScriptHash)
+		require.NotNil(t, entry)
+		assert.Equal(t, uint32(99), entry.BlockHeight)
+		assert.Equal(t, []uint32{99}, downloader.heights)
+	})
+
+	t.Run("should download the locked height when frozen", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{
+			{"getstateroot", stateRootResult},
+			{"getstate", historicContractStateResult(t)},
+			{"findstates", `{"jsonrpc":"2.0","id":1,"result":{"results":[],"truncated":false}}`},
+		})
+		defer srv.Close()
+		cfg.Networks = []NetworkConfig{{Label: network, Hosts: []string{srv.URL}}}
+
+		height := uint32(5)
+		c := ContractConfig{Label: "A", SourceNetwork: &network, SourceHeight: &height, Download: &download, GenerateSdk: &generateSdk}
+		lock := &LockFile{}
+		require.NoError(t, processContract(&c, NewOkDownloader(), lock, false))
+
+		c.SourceHeight = nil
+		downloader := &MockDownloader{responses: []bool{true}}
+		require.NoError(t, processContract(&c, downloader, lock, true))
+		assert.Equal(t, []uint32{5}, downloader.heights)
+	})
+}
+
 func Test_DownloadManifest(t *testing.T) {
 	log.SetLevel(log.WarnLevel)
 	t.Cleanup(func() { log.SetOutput(os.Stderr) })
@@ -93,7 +437,7 @@ func Test_DownloadManifest(t *testing.T) {
 		logs := NewMockLogs(t)
 		contractStateResponse := RpcResponse{
 			"getcontractstate",
-			`{"jsonrpc":"2.0","id":0,"result":{"id":1,"updatecounter":0,"hash":"0x16ce77fbb91be1d4aa1e2b58f1141d747bdf2666","nef":{"magic":860243278,"compiler":"neo3-boa by COZ-1.0.0","source":"","tokens":[{"hash":"0xfffdc93764dbaddd97c48f252a53ea4643faa3fd","method":"update","paramcount":3,"hasreturnvalue":false,"callflags":"All"}],"script":"DAVGSVJTVEBXAAN6eXg3AABA","checksum":3884080072},"manifest":{"name":"01-simple","groups":[],"features":{},"supportedstandards":[],"abi":{"methods":[{"name":"main","parameters":[],"returntype":"String","offset":0,"safe":false},{"name":"update","parameters":[{"name":"script","type":"ByteArray"},{"name":"manifest","type":"ByteArray"},{"name":"data","type":"Any"}],"returntype":"Void","offset":8,"safe":false}],"events":[]},"permissions":[{"contract":"0xfffdc93764dbaddd97c48f252a53ea4643faa3fd","methods":["update"]}],"trusts":[],"extra":null}}}`,
+			contractStateResult,
 		}
 		srv := NewTestRpcServer(t, []RpcResponse{contractStateResponse})
 		defer srv.Close()
@@ -144,12 +488,17 @@ type MockDownloader struct {
 	responses   []bool
 	ctr         int
 	responseMsg []string
+	// heights holds the block heights that downloads were requested at
+	heights []uint32
 }
 
-func (md *MockDownloader) downloadContract(scriptHash util.Uint160, host string) (string, error) {
+func (md *MockDownloader) downloadContract(scriptHash util.Uint160, host string, height *uint32) (string, error) {
 	if md.ctr > len(md.responses) {
 		return "", fmt.Errorf("insufficient responses")
 	}
+	if height != nil {
+		md.heights = append(md.heights, *height)
+	}
 	r := md.responses[md.ctr]
 	md.ctr++
 	if r {
@@ -159,7 +508,7 @@ func (md *MockDownloader) downloadContract(scriptHash util.Uint160, host string)
 	} else {
 		s := fmt.Sprintf("download failed, c = %s, h = %s", scriptHash.StringLE(), host)
 		md.responseMsg = append(md.responseMsg, s)
-		return s, errors.New("download failed")
+		return s, &hostError{errors.New("download failed")}
 	}
 }
 
@@ -204,21 +553,26 @@ type JsonRPC struct {
 	Version string        `json:"jsonrpc"`
 }
 
+// getcontractstate response for a simple neo3-boa compiled contract
+const contractStateResult = `{"jsonrpc":"2.0","id":0,"result":{"id":1,"updatecounter":0,"hash":"0x16ce77fbb91be1d4aa1e2b58f1141d747bdf2666","nef":{"magic":860243278,"compiler":"neo3-boa by COZ-1.0.0","source":"","tokens":[{"hash":"0xfffdc93764dbaddd97c48f252a53ea4643faa3fd","method":"update","paramcount":3,"hasreturnvalue":false,"callflags":"All"}],"script":"DAVGSVJTVEBXAAN6eXg3AABA","checksum":3884080072},"manifest":{"name":"01-simple","groups":[],"features":{},"supportedstandards":[],"abi":{"methods":[{"name":"main","parameters":[],"returntype":"String","offset":0,"safe":false},{"name":"update","parameters":[{"name":"script","type":"ByteArray"},{"name":"manifest","type":"ByteArray"},{"name":"data","type":"Any"}],"returntype":"Void","offset":8,"safe":false}],"events":[]},"permissions":[{"contract":"0xfffdc93764dbaddd97c48f252a53ea4643faa3fd","methods":["update"]}],"trusts":[],"extra":null}}}`
+
 type RpcResponse struct {
-	Method         string // RPC method that's called. This is just for readability, it's not actually used
+	Method         string // RPC method that's called
 	ServerResponse string // The RPC server response to the method
 }
 
-// MockRpcServer is a basic JSON-RPC server that for reach call returns the next response from an internal list
-// of responses
+// MockRpcServer is a basic JSON-RPC server that for each call returns the next response for the called method from an
+// internal list of responses. The last response of a method is repeated once all its responses are used
 type MockRpcServer struct {
-	counter   int
+	mu        sync.Mutex
+	served    map[string]int
 	responses []RpcResponse
 }
 
 func NewMockRpcServer() *MockRpcServer {
 	// neo-go RPC clients call the follow methods during initialisation
 	return &MockRpcServer{
+		served: make(map[string]int),
 		responses: []RpcResponse{
 			RpcResponse{
 				"getversion",
@@ -244,21 +598,86 @@ func (mrs *MockRpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("failed to decode JSON-RPC request: %v", err), http.StatusBadRequest)
 		return
 	}
-	if mrs.counter >= len(mrs.responses) {
-		http.Error(w, fmt.Sprintf("requested '%s', not enough responses in mock server", req.Method), http.StatusBadRequest)
+
+	response, err := mrs.response(req.Method)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	_, err = w.Write([]byte(mrs.responses[mrs.counter].ServerResponse))
+
+	_, err = w.Write([]byte(response))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to write response: %v", err), http.StatusBadRequest)
 		return
 	}
-	mrs.counter++
 }
 
-// Caller must call Close() when done
+// response returns the next response for the method
+func (mrs *MockRpcServer) response(method string) (string, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	var responses []RpcResponse
+	for _, r := range mrs.responses {
+		if r.Method == method {
+			responses = append(responses, r)
+		}
+	}
+	if len(responses) == 0 {
+		return "", fmt.Errorf("requested '%s', no response for it in mock server", method)
+	}
+	i := min(mrs.served[method], len(responses)-1)
+	mrs.served[method]++
+	return responses[i].ServerResponse, nil
+}
+
+// ServeWebSocket serves the responses over a WebSocket connection. The response ID is replaced by the request ID as
+// WebSocket clients match responses to requests by ID
+func (mrs *MockRpcServer) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		req := JsonRPC{}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		response, err := mrs.response(req.Method)
+		if err != nil {
+			response = fmt.Sprintf(`{"jsonrpc":"2.0","id":0,"error":{"code":-32601,"message":"%s"}}`, err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal([]byte(response), &msg); err != nil {
+			return
+		}
+		msg["id"] = req.Id
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// Caller must call Close() when done. Contracts fetched from the server are cached in a temporary directory
 func NewTestRpcServer(t *testing.T, responses []RpcResponse) *httptest.Server {
+	return httptest.NewServer(newTestMockRpcServer(t, responses))
+}
+
+// NewTestWebSocketRpcServer is like NewTestRpcServer but serves over WebSocket. Use "ws" + strings.TrimPrefix(srv.URL,
+// "http") as host
+func NewTestWebSocketRpcServer(t *testing.T, responses []RpcResponse) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(newTestMockRpcServer(t, responses).ServeWebSocket))
+}
+
+func newTestMockRpcServer(t *testing.T, responses []RpcResponse) *MockRpcServer {
+	t.Setenv(CACHE_DIR_ENV, t.TempDir())
+	// clients are pooled by host and the ports of closed servers are reused
+	t.Cleanup(clients.close)
 	m := NewMockRpcServer()
+	// the block height is requested to record at which height the contract state was fetched
+	m.responses = append(m.responses, RpcResponse{"getblockcount", `{"jsonrpc":"2.0","id":1,"result":100}`})
 	m.responses = append(m.responses, responses...)
-	return httptest.NewServer(m)
+	return m
 }
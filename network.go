@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// DEFAULT_BACKOFF is the delay before the first retry of a failed host if the network does not specify a 'backoff'
+const DEFAULT_BACKOFF = time.Second
+
+// DEFAULT_TIMEOUT is the dial and request timeout of RPC calls if the network does not specify a 'timeout'
+const DEFAULT_TIMEOUT = 4 * time.Second
+
+// appCtx is cancelled when the user interrupts cpm (i.e. Ctrl-C)
+var appCtx = context.Background()
+
+// networkForHost returns the configured network that lists the host, or nil if the host is not part of any network
+func (c *CPMConfig) networkForHost(host string) *NetworkConfig {
+	for i := range c.Networks {
+		for _, h := range c.Networks[i].Hosts {
+			if h == host {
+				return &c.Networks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// hostSettings holds the connection settings of the network a host belongs to
+type hostSettings struct {
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+func (c *CPMConfig) hostSettings(host string) hostSettings {
+	settings := hostSettings{timeout: DEFAULT_TIMEOUT, backoff: DEFAULT_BACKOFF}
+	network := c.networkForHost(host)
+	if network == nil {
+		return settings
+	}
+	if network.Timeout != nil {
+		settings.timeout = *network.Timeout
+	}
+	if network.Retries != nil {
+		settings.retries = *network.Retries
+	}
+	if network.Backoff != nil {
+		settings.backoff = *network.Backoff
+	}
+	return settings
+}
+
+// verifyNetworkMagic returns an error if the host belongs to a network with an expected magic that differs from the
+// magic the host reported
+func verifyNetworkMagic(host string, magic uint32) error {
+	network := cfg.networkForHost(host)
+	if network == nil || network.Magic == nil || *network.Magic == magic {
+		return nil
+	}
+	return fmt.Errorf("host %s has network magic %d but network '%s' expects %d", host, magic, network.Label, *network.Magic)
+}
+
+// verifyHost verifies the network magic of the host against the expected network magic. Hosts of networks without an
+// expected magic are not contacted
+func verifyHost(host string) error {
+	network := cfg.networkForHost(host)
+	if network == nil || network.Magic == nil {
+		return nil
+	}
+	_, err := clients.get(host)
+	return err
+}
+
+// rpcClient is an initialized RPC client together with the network magic reported by its host
+type rpcClient struct {
+	*rpcclient.Client
+	magic uint32
+	// close closes the client, which for WebSocket hosts also closes the connection
+	close func()
+}
+
+type poolEntry struct {
+	mu     sync.Mutex
+	done   bool
+	client *rpcClient
+	err    error
+}
+
+// clientPool holds one RPC client per host for the duration of a command. Initialization errors are kept as well, so a
+// host that is down is not contacted again for every contract
+type clientPool struct {
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+}
+
+var clients = &clientPool{entries: make(map[string]*poolEntry)}
+
+// get returns the client for the host, creating and initializing it on first use. The client uses the timeout of the
+// network the host belongs to and is verified to be connected to the expected network. The initialization time is
+// recorded as latency of the host to order hosts by health
+func (p *clientPool) get(host string) (*rpcClient, error) {
+	p.mu.Lock()
+	entry, ok := p.entries[host]
+	if !ok {
+		entry = &poolEntry{}
+		p.entries[host] = entry
+	}
+	p.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if !entry.done {
+		entry.client, entry.err = newClient(host)
+		entry.done = true
+	}
+	return entry.client, entry.err
+}
+
+// retry forgets the initialization error of the host, so that the next call to get tries to initialize it again
+func (p *clientPool) retry(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.entries[host]; ok && entry.err != nil {
+		delete(p.entries, host)
+	}
+}
+
+// close closes all clients and empties the pool
+func (p *clientPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range p.entries {
+		if entry.client != nil {
+			entry.client.close()
+		}
+	}
+	p.entries = make(map[string]*poolEntry)
+}
+
+func newClient(host string) (*rpcClient, error) {
+	settings := cfg.hostSettings(host)
+
+	start := time.Now()
+	client, closeClient, err := dialHost(appCtx, host, settings.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RPC client: %w", err)
+	}
+	result := &rpcClient{Client: client, close: closeClient}
+
+	err = result.Init()
+	if err != nil {
+		result.close()
+		return nil, fmt.Errorf("RPCClient init failed with: %w", err)
+	}
+	health.latency(host, time.Since(start))
+
+	// Init requests the version as well but does not expose the network magic
+	version, err := result.GetVersion()
+	if err != nil {
+		result.close()
+		return nil, fmt.Errorf("getversion failed with: %w", err)
+	}
+	result.magic = uint32(version.Protocol.Network)
+	if err := verifyNetworkMagic(host, result.magic); err != nil {
+		result.close()
+		return nil, &hostError{err}
+	}
+	return result, nil
+}
+
+// isWebSocketHost returns true for ws:// and wss:// hosts
+func isWebSocketHost(host string) bool {
+	u, err := url.Parse(host)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// tryHosts calls fn for each host, healthiest first, until it succeeds. A host that fails with a host error (see
+// isHostError) is retried with exponential backoff according to the settings of its network before it is skipped.
+// Every attempt is recorded to order hosts by health. The reason for skipping a host is logged at skipLevel. Any other
+// error would be the same on every host and is returned right away. Returns the error of the last attempt if all hosts
+// fail. The RPC clients abort their requests when appCtx is cancelled, so fn returns early on interruption
+func tryHosts(hosts []string, skipLevel log.Level, fn func(host string) error) error {
+	lastErr := errors.New("no hosts to try")
+	for _, host := range health.order(hosts) {
+		settings := cfg.hostSettings(host)
+		for attempt := 0; ; attempt++ {
+			err := fn(host)
+			if err == nil {
+				health.success(host)
+				return nil
+			}
+			if appCtx.Err() != nil {
+				return appCtx.Err()
+			}
+			if !isHostError(err) {
+				return err
+			}
+			health.failure(host)
+			lastErr = err
+			if attempt >= settings.retries {
+				log.StandardLogger().Logf(skipLevel, "Skipping host %s: %v", host, err)
+				break
+			}
+
+			delay := settings.backoff << attempt
+			log.Debugf("Attempt %d of %d using %s failed: %v. Retrying in %s", attempt+1, settings.retries+1, host, err, delay)
+			select {
+			case <-time.After(delay):
+			case <-appCtx.Done():
+				return appCtx.Err()
+			}
+			clients.retry(host)
+		}
+	}
+	return lastErr
+}
+
+// hostError is an error of the host that is not recognized by its type, i.e. an HTTP error status or a host that belongs
+// to another network
+type hostError struct {
+	err error
+}
+
+func (e *hostError) Error() string { return e.err.Error() }
+func (e *hostError) Unwrap() error { return e.err }
+
+// isHostError returns true if err was caused by the host rather than by the request, so that another attempt or host may
+// succeed. These are transport errors and JSON-RPC errors, except the errors for a contract or storage item that does
+// not exist, which would be the same on every host
+func isHostError(err error) bool {
+	var rpcErr *neorpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code != neorpc.ErrUnknownContractCode && rpcErr.Code != neorpc.ErrUnknownStorageItemCode
+	}
+	var hostErr *hostError
+	var netErr net.Error
+	return errors.As(err, &hostErr) || errors.As(err, &netErr) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, rpcclient.ErrWSConnLost)
+}
+
+type hostStats struct {
+	latency time.Duration
+	// failures is the number of failures since the last success
+	failures int
+}
+
+// hostHealth keeps track of the latency and recent failures of the hosts contacted during the command
+type hostHealth struct {
+	mu    sync.Mutex
+	stats map[string]*hostStats
+}
+
+var health = &hostHealth{stats: make(map[string]*hostStats)}
+
+func (h *hostHealth) get(host string) *hostStats {
+	s, ok := h.stats[host]
+	if !ok {
+		s = &hostStats{latency: -1}
+		h.stats[host] = s
+	}
+	return s
+}
+
+// latency records the time the host took to answer the first requests
+func (h *hostHealth) latency(host string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.get(host).latency = latency
+}
+
+func (h *hostHealth) success(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.get(host).failures = 0
+}
+
+func (h *hostHealth) failure(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.get(host).failures++
+}
+
+// order returns the hosts sorted by recent failures and then by latency. Hosts that were not contacted yet come after
+// hosts with a known latency. The configured order is kept otherwise
+func (h *hostHealth) order(hosts []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	type entry struct {
+		host  string
+		stats hostStats
+	}
+	entries := make([]entry, len(hosts))
+	for i, host := range hosts {
+		entries[i] = entry{host, *h.get(host)}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].stats, entries[j].stats
+		if a.failures != b.failures {
+			return a.failures < b.failures
+		}
+		if (a.latency < 0) != (b.latency < 0) {
+			return a.latency >= 0
+		}
+		return a.latency < b.latency
+	})
+
+	ordered := make([]string, len(entries))
+	for i, e := range entries {
+		ordered[i] = e.host
+	}
+	return ordered
+}
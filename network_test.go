@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NetworkMagic(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := util.Uint160{}
+	networks := cfg.Networks
+	t.Cleanup(func() { cfg.Networks = networks })
+
+	t.Run("should refuse host with different magic", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		defer srv.Close()
+
+		// the mock server reports the MainNet magic 860833102
+		magic := uint32(894710606)
+		cfg.Networks = []NetworkConfig{{Label: "testnet", Hosts: []string{srv.URL}, Magic: &magic}}
+
+		_, err := fetchManifest(&c, srv.URL, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has network magic 860833102 but network 'testnet' expects 894710606")
+
+		err = verifyHost(srv.URL)
+		require.Error(t, err)
+	})
+
+	t.Run("should accept host with expected magic", func(t *testing.T) {
+		srv := NewTestRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		defer srv.Close()
+
+		magic := uint32(860833102)
+		cfg.Networks = []NetworkConfig{{Label: "mainnet", Hosts: []string{srv.URL}, Magic: &magic}}
+
+		m, err := fetchManifest(&c, srv.URL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+		assert.NoError(t, verifyHost(srv.URL))
+	})
+}
+
+func Test_TryHosts(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	networks, hosts := cfg.Networks, health
+	t.Cleanup(func() { cfg.Networks, health = networks, hosts })
+	resetHealth := func() { health = &hostHealth{stats: make(map[string]*hostStats)} }
+
+	t.Run("should retry host before trying the next host", func(t *testing.T) {
+		retries := 2
+		backoff := time.Millisecond
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{"a", "b"}, Retries: &retries, Backoff: &backoff}}
+		resetHealth()
+
+		var calls []string
+		err := tryHosts([]string{"a", "b"}, log.WarnLevel, func(host string) error {
+			calls = append(calls, host)
+			if host == "a" {
+				return &hostError{errors.New("unavailable")}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "a", "a", "b"}, calls)
+	})
+
+	t.Run("should return last error if all hosts fail", func(t *testing.T) {
+		cfg.Networks = nil
+		resetHealth()
+		err := tryHosts([]string{"a", "b"}, log.WarnLevel, func(host string) error {
+			return &hostError{errors.New(host + " unavailable")}
+		})
+		require.Error(t, err)
+		assert.Equal(t, "b unavailable", err.Error())
+	})
+
+	t.Run("should not retry errors that are the same on every host", func(t *testing.T) {
+		cfg.Networks = nil
+		resetHealth()
+		var calls []string
+		err := tryHosts([]string{"a", "b"}, log.WarnLevel, func(host string) error {
+			calls = append(calls, host)
+			return fmt.Errorf("getcontractstate failed with: %w", neorpc.ErrUnknownContract)
+		})
+		require.ErrorIs(t, err, neorpc.ErrUnknownContract)
+		assert.Equal(t, []string{"a"}, calls)
+		assert.Equal(t, []string{"a", "b"}, health.order([]string{"a", "b"}))
+	})
+
+	t.Run("should only take transport and RPC errors for host errors", func(t *testing.T) {
+		assert.True(t, isHostError(fmt.Errorf("getversion failed with: %w", &net.OpError{Op: "dial", Err: errors.New("refused")})))
+		assert.True(t, isHostError(fmt.Errorf("getstate failed with: %w", neorpc.NewInternalServerError("busy"))))
+		assert.True(t, isHostError(&hostError{errors.New("HTTP 502/Bad Gateway")}))
+		assert.False(t, isHostError(fmt.Errorf("getstate failed with: %w", neorpc.ErrUnknownStorageItem)))
+		assert.False(t, isHostError(errors.New("can't create output file")))
+	})
+
+	t.Run("should order hosts by failures and latency", func(t *testing.T) {
+		h := &hostHealth{stats: make(map[string]*hostStats)}
+		h.latency("slow", 200*time.Millisecond)
+		h.latency("fast", 10*time.Millisecond)
+		h.latency("broken", time.Millisecond)
+		h.failure("broken")
+
+		assert.Equal(t, []string{"fast", "slow", "unknown", "broken"}, h.order([]string{"broken", "unknown", "slow", "fast"}))
+	})
+
+	t.Run("should try hosts that failed a call last", func(t *testing.T) {
+		cfg.Networks = nil
+		resetHealth()
+		fail := true
+		err := tryHosts([]string{"a", "b"}, log.WarnLevel, func(host string) error {
+			if host == "a" && fail {
+				return &hostError{errors.New("unavailable")}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		var calls []string
+		fail = false
+		err = tryHosts([]string{"a", "b"}, log.WarnLevel, func(host string) error {
+			calls = append(calls, host)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b"}, calls)
+		assert.Equal(t, []string{"b", "a"}, health.order([]string{"a", "b"}))
+	})
+}
+
+func Test_ClientPool(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	t.Run("should share clients per host", func(t *testing.T) {
+		srv := NewTestRpcServer(t, nil)
+		defer srv.Close()
+
+		first, err := clients.get(srv.URL)
+		require.NoError(t, err)
+		second, err := clients.get(srv.URL)
+		require.NoError(t, err)
+		assert.Same(t, first, second)
+		assert.Equal(t, uint32(860833102), first.magic)
+	})
+
+	t.Run("should connect to WebSocket hosts", func(t *testing.T) {
+		srv := NewTestWebSocketRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		defer srv.Close()
+		host := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+		client, err := clients.get(host)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(860833102), client.magic)
+
+		m, err := fetchManifest(&util.Uint160{}, host, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+	})
+
+	t.Run("should cache init errors until retried", func(t *testing.T) {
+		pool := &clientPool{entries: make(map[string]*poolEntry)}
+		host := "http://127.0.0.1:10333"
+
+		_, err := pool.get(host)
+		require.Error(t, err)
+		entry := pool.entries[host]
+
+		_, err2 := pool.get(host)
+		assert.Equal(t, err, err2)
+		assert.Same(t, entry, pool.entries[host])
+
+		pool.retry(host)
+		assert.NotContains(t, pool.entries, host)
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func handleCliOutdated(cCtx *cli.Context) error {
+	LoadConfig()
+	lock, err := loadLockFile(lockFilePath())
+	if err != nil {
+		return err
+	}
+
+	upToDate := true
+	for _, c := range cfg.Contracts {
+		if isPinned(&c, lock) {
+			fmt.Printf("%s (0x%s) is pinned at %d\n", c.Label, c.ScriptHash.StringLE(), *c.SourceHeight)
+			continue
+		}
+		changes, err := contractChanges(&c, lock)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		upToDate = false
+		fmt.Printf("%s (0x%s)\n", c.Label, c.ScriptHash.StringLE())
+		for _, change := range changes {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+
+	if upToDate {
+		fmt.Println("All contracts are up to date")
+	}
+	return nil
+}
+
+func handleCliUpdate(cCtx *cli.Context) error {
+	LoadConfig()
+	lock, err := loadLockFile(lockFilePath())
+	if err != nil {
+		return err
+	}
+
+	var contracts []ContractConfig
+	if cCtx.NArg() > 0 {
+		c, err := findContract(cCtx.Args().First())
+		if err != nil {
+			return err
+		}
+		if isPinned(c, lock) {
+			logPinned(c)
+			return nil
+		}
+		contracts = append(contracts, *c)
+	} else {
+		for _, c := range cfg.Contracts {
+			if isPinned(&c, lock) {
+				logPinned(&c)
+				continue
+			}
+			changes, err := contractChanges(&c, lock)
+			if err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				contracts = append(contracts, c)
+			}
+		}
+	}
+
+	if len(contracts) == 0 {
+		log.Info("All contracts are up to date")
+		return nil
+	}
+
+	downloader := NewDownloader(cfg.Defaults.ContractDestination, cfg.resolvePath(cfg.Tools.NeoExpress.ConfigPath))
+	for _, c := range contracts {
+		if err := processContract(&c, downloader, lock, false); err != nil {
+			return err
+		}
+		log.Infof("Updated contract '%s' (%s)", c.Label, c.ScriptHash.StringLE())
+	}
+
+	if err := lock.saveToDisk(lockFilePath()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", lockFilePath(), err)
+	}
+	return nil
+}
+
+// findContract returns the contract config matching the label or script hash
+func findContract(labelOrHash string) (*ContractConfig, error) {
+	i, err := findContractIndex(labelOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Contracts[i], nil
+}
+
+// findContractIndex returns the index of the contract config matching the label or script hash
+func findContractIndex(labelOrHash string) (int, error) {
+	scriptHash, hashErr := util.Uint160DecodeStringLE(strings.TrimPrefix(labelOrHash, "0x"))
+	for i, c := range cfg.Contracts {
+		if c.Label == labelOrHash || (hashErr == nil && c.ScriptHash.Equals(scriptHash)) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no contract with label or script hash '%s' found in %s", labelOrHash, cfg.path)
+}
+
+// isPinned returns true if the contract is pinned to a source height and the state at that height is recorded in the
+// lock file. Such a contract only changes when its 'source-height' is changed, so outdated and update skip it
+func isPinned(c *ContractConfig, lock *LockFile) bool {
+	locked := lock.get(c.ScriptHash)
+	return c.SourceHeight != nil && locked != nil && locked.BlockHeight == *c.SourceHeight
+}
+
+func logPinned(c *ContractConfig) {
+	log.Infof("Skipping contract '%s' (%s), it is pinned at %d. Change 'source-height' in %s to update it",
+		c.Label, c.ScriptHash.StringLE(), *c.SourceHeight, cfg.path)
+}
+
+// contractChanges compares the latest state of the contract on the source network with the state recorded in the lock
+// file and returns a human-readable description of each difference. For a contract pinned to another height than the
+// one recorded, that is the only difference
+func contractChanges(c *ContractConfig, lock *LockFile) ([]string, error) {
+	locked := lock.get(c.ScriptHash)
+	if locked == nil {
+		return []string{fmt.Sprintf("not in %s, run 'cpm run' to record its state", lockFilePath())}, nil
+	}
+	if c.SourceHeight != nil && *c.SourceHeight != locked.BlockHeight {
+		return []string{fmt.Sprintf("source height: %d -> %d", locked.BlockHeight, *c.SourceHeight)}, nil
+	}
+
+	latest := *c
+	latest.SourceHeight = nil
+	hosts, err := cfg.getHosts(*c.SourceNetwork)
+	if err != nil {
+		return nil, err
+	}
+	current, err := fetchLockEntry(&latest, hosts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch state of contract '%s' (%s): %w", c.Label, c.ScriptHash.StringLE(), err)
+	}
+
+	var changes []string
+	if locked.UpdateCounter != current.UpdateCounter {
+		changes = append(changes, fmt.Sprintf("update counter: %d -> %d", locked.UpdateCounter, current.UpdateCounter))
+	}
+	if locked.NefChecksum != current.NefChecksum {
+		changes = append(changes, fmt.Sprintf("NEF checksum: %d -> %d", locked.NefChecksum, current.NefChecksum))
+	}
+	if locked.ManifestDigest != current.ManifestDigest {
+		abiChanges := diffAbi(locked.Abi, current.Abi)
+		if len(abiChanges) == 0 {
+			changes = append(changes, "manifest changed without ABI changes")
+		}
+		changes = append(changes, abiChanges...)
+	}
+	return changes, nil
+}
+
+// diffAbi returns the removed (-) and added (+) signatures
+func diffAbi(old, new []string) []string {
+	var changes []string
+	for _, sig := range old {
+		if !slices.Contains(new, sig) {
+			changes = append(changes, "- "+sig)
+		}
+	}
+	for _, sig := range new {
+		if !slices.Contains(old, sig) {
+			changes = append(changes, "+ "+sig)
+		}
+	}
+	return changes
+}
+
+// abiSignatures returns a signature per method and event in the manifest, i.e. `method transfer(from Hash160, to
+// Hash160, amount Integer, data Any) Boolean`
+func abiSignatures(m *manifest.Manifest) []string {
+	params := func(parameters []manifest.Parameter) string {
+		var p []string
+		for _, param := range parameters {
+			p = append(p, param.Name+" "+param.Type.String())
+		}
+		return strings.Join(p, ", ")
+	}
+
+	var signatures []string
+	for _, method := range m.ABI.Methods {
+		sig := fmt.Sprintf("method %s(%s) %s", method.Name, params(method.Parameters), method.ReturnType)
+		if method.Safe {
+			sig += " safe"
+		}
+		signatures = append(signatures, sig)
+	}
+	for _, event := range m.ABI.Events {
+		signatures = append(signatures, fmt.Sprintf("event %s(%s)", event.Name, params(event.Parameters)))
+	}
+	return signatures
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AbiChanges(t *testing.T) {
+	m := manifest.NewManifest("sample")
+	m.ABI.Methods = []manifest.Method{
+		{Name: "balanceOf", Parameters: []manifest.Parameter{{Name: "account", Type: smartcontract.Hash160Type}}, ReturnType: smartcontract.IntegerType, Safe: true},
+	}
+	m.ABI.Events = []manifest.Event{
+		{Name: "Transfer", Parameters: []manifest.Parameter{{Name: "from", Type: smartcontract.Hash160Type}}},
+	}
+	old := abiSignatures(m)
+	assert.Equal(t, []string{"method balanceOf(account Hash160) Integer safe", "event Transfer(from Hash160)"}, old)
+
+	m.ABI.Methods[0].Parameters = append(m.ABI.Methods[0].Parameters, manifest.Parameter{Name: "token", Type: smartcontract.ByteArrayType})
+	changes := diffAbi(old, abiSignatures(m))
+	assert.Equal(t, []string{
+		"- method balanceOf(account Hash160) Integer safe",
+		"+ method balanceOf(account Hash160, token ByteArray) Integer safe",
+	}, changes)
+}
+
+func Test_PinnedContracts(t *testing.T) {
+	pin, other := uint32(5), uint32(7)
+	lock := &LockFile{Contracts: []LockEntry{{Label: "A", BlockHeight: pin}}}
+
+	t.Run("should skip contracts pinned at the recorded height", func(t *testing.T) {
+		assert.True(t, isPinned(&ContractConfig{Label: "A", SourceHeight: &pin}, lock))
+		assert.False(t, isPinned(&ContractConfig{Label: "A"}, lock))
+		assert.False(t, isPinned(&ContractConfig{Label: "A", SourceHeight: &other}, lock))
+		assert.False(t, isPinned(&ContractConfig{Label: "A", SourceHeight: &pin}, &LockFile{}))
+	})
+
+	t.Run("should report a changed pin", func(t *testing.T) {
+		changes, err := contractChanges(&ContractConfig{Label: "A", SourceHeight: &other}, lock)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"source height: 5 -> 7"}, changes)
+	})
+}
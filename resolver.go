@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/native/nativenames"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/address"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/invoker"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/nns"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	REF_PREFIX_NNS    = "nns:"
+	REF_PREFIX_NATIVE = "native:"
+)
+
+// MainNetNNSHash is the script hash of the NameService contract on MainNet
+var MainNetNNSHash = util.Uint160{0xde, 0x46, 0x5f, 0x5d, 0x50, 0x57, 0xcf, 0x33, 0x28, 0x47, 0x94, 0xc5, 0xcf, 0xc2, 0x0c, 0x69, 0x37, 0x1c, 0xac, 0x50}
+
+// resolveContractHashes resolves the script hash of all contracts that are configured by NNS domain or native contract
+// name and do not have a script hash yet. Returns true if any contract was resolved
+func (c *CPMConfig) resolveContractHashes() (bool, error) {
+	resolved := false
+	for i, contract := range c.Contracts {
+		if !contract.ScriptHash.Equals(util.Uint160{}) {
+			continue
+		}
+
+		var ref string
+		if contract.Native != nil {
+			ref = REF_PREFIX_NATIVE + *contract.Native
+		} else if contract.NNS != nil {
+			ref = REF_PREFIX_NNS + *contract.NNS
+		} else {
+			continue
+		}
+
+		network, err := c.getNetwork(*contract.SourceNetwork)
+		if err != nil {
+			return resolved, err
+		}
+		scriptHash, err := resolveScriptHash(ref, network.Hosts, network.nnsHash())
+		if err != nil {
+			return resolved, fmt.Errorf("failed to resolve script hash of contract '%s': %w", contract.Label, err)
+		}
+		log.Infof("Resolved contract '%s' (%s) to %s", contract.Label, ref, scriptHash.StringLE())
+		c.Contracts[i].ScriptHash = scriptHash
+		resolved = true
+	}
+	return resolved, nil
+}
+
+func (n *NetworkConfig) nnsHash() util.Uint160 {
+	if n == nil || n.NNS == nil {
+		return MainNetNNSHash
+	}
+	return *n.NNS
+}
+
+// resolveScriptHash returns the script hash for a contract reference. A reference is either a script hash, a native
+// contract name (optionally prefixed with 'native:') or an NNS domain (optionally prefixed with 'nns:')
+func resolveScriptHash(ref string, hosts []string, nnsHash util.Uint160) (util.Uint160, error) {
+	switch {
+	case strings.HasPrefix(ref, REF_PREFIX_NATIVE):
+		return resolveNativeHash(strings.TrimPrefix(ref, REF_PREFIX_NATIVE))
+	case strings.HasPrefix(ref, REF_PREFIX_NNS):
+		return resolveNNS(strings.TrimPrefix(ref, REF_PREFIX_NNS), hosts, nnsHash)
+	}
+
+	if scriptHash, err := util.Uint160DecodeStringLE(strings.TrimPrefix(ref, "0x")); err == nil {
+		return scriptHash, nil
+	}
+	if nativenames.IsValid(ref) {
+		return resolveNativeHash(ref)
+	}
+	if strings.Contains(ref, ".") {
+		return resolveNNS(ref, hosts, nnsHash)
+	}
+	return util.Uint160{}, fmt.Errorf("'%s' is not a script hash, native contract name or NNS domain", ref)
+}
+
+func resolveNativeHash(name string) (util.Uint160, error) {
+	if !nativenames.IsValid(name) {
+		return util.Uint160{}, fmt.Errorf("unknown native contract '%s'. Valid names are %s", name, strings.Join(nativenames.All, ", "))
+	}
+	return state.CreateNativeContractHash(name), nil
+}
+
+// resolveNNS resolves the TXT record of the domain, which is expected to hold a script hash or address
+func resolveNNS(domain string, hosts []string, nnsHash util.Uint160) (util.Uint160, error) {
+	if offline {
+		return util.Uint160{}, fmt.Errorf("can't resolve NNS domain '%s' in offline mode", domain)
+	}
+	var record string
+	err := tryHosts(hosts, log.DebugLevel, func(host string) error {
+		var err error
+		record, err = resolveNNSRecord(domain, host, nnsHash)
+		return err
+	})
+	if err != nil {
+		return util.Uint160{}, err
+	}
+
+	if scriptHash, err := util.Uint160DecodeStringLE(strings.TrimPrefix(record, "0x")); err == nil {
+		return scriptHash, nil
+	}
+	if scriptHash, err := address.StringToUint160(record); err == nil {
+		return scriptHash, nil
+	}
+	return util.Uint160{}, fmt.Errorf("TXT record '%s' of '%s' is not a script hash or address", record, domain)
+}
+
+func resolveNNSRecord(domain, host string, nnsHash util.Uint160) (string, error) {
+	client, err := clients.get(host)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("Attempting to resolve '%s' using %s", domain, host)
+	record, err := nns.NewReader(invoker.New(client.Client, nil), nnsHash).Resolve(domain, nns.TXT)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve '%s': %w", domain, err)
+	}
+	return record, nil
+}
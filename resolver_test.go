@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/native/nativehashes"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResolveScriptHash(t *testing.T) {
+	assert.Equal(t, "50ac1c37690cc2cfc594472833cf57505d5f46de", MainNetNNSHash.StringLE())
+
+	t.Run("script hash", func(t *testing.T) {
+		h, err := resolveScriptHash("0x4380f2c1de98bb267d3ea821897ec571a04fe3e0", nil, MainNetNNSHash)
+		require.NoError(t, err)
+		assert.Equal(t, "4380f2c1de98bb267d3ea821897ec571a04fe3e0", h.StringLE())
+	})
+
+	t.Run("native contract", func(t *testing.T) {
+		for _, ref := range []string{"GasToken", "native:GasToken"} {
+			h, err := resolveScriptHash(ref, nil, MainNetNNSHash)
+			require.NoError(t, err)
+			assert.Equal(t, nativehashes.GasToken, h)
+		}
+
+		_, err := resolveScriptHash("native:FooToken", nil, MainNetNNSHash)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown native contract 'FooToken'")
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		_, err := resolveScriptHash("invalidhash", nil, util.Uint160{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"cpm/generators"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// handleCliTemplatesExport writes the built-in templates of the languages, or of all languages, to
+// '<folder>/<sdk type>/<language>/<template name>.tmpl', which is the layout 'templates' in cpm.yaml expects
+func handleCliTemplatesExport(cCtx *cli.Context) error {
+	languages := cCtx.Args().Slice()
+	for _, l := range languages {
+		g := generators.Get(l)
+		if g == nil || !hasTemplates(g) {
+			return fmt.Errorf("language '%s' has no templates. Valid values are %s", l, strings.Join(templateLanguages(), ", "))
+		}
+	}
+	if len(languages) == 0 {
+		languages = templateLanguages()
+	}
+
+	root := cCtx.String("o")
+	for _, l := range languages {
+		g := generators.Get(l)
+		for _, sdkType := range g.SDKTypes() {
+			templates := g.Templates(sdkType)
+			dir := filepath.Join(root, sdkType, l)
+			for name, text := range templates {
+				path := filepath.Join(dir, name+generators.TemplateExt)
+				if _, err := os.Stat(path); err == nil && !cCtx.Bool("force") {
+					return fmt.Errorf("%s already exists. Use '--force' to overwrite it", path)
+				}
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return fmt.Errorf("can't create directory %s: %w", dir, err)
+				}
+				if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+			}
+			if templates != nil {
+				log.Infof("Exported %s %s templates to %s", sdkTypeKeys[sdkType], l, dir)
+			}
+		}
+	}
+	return nil
+}
+
+func hasTemplates(g generators.Generator) bool {
+	return slices.ContainsFunc(g.SDKTypes(), func(sdkType string) bool {
+		return g.Templates(sdkType) != nil
+	})
+}
+
+// templateLanguages returns the languages whose SDKs are generated from templates
+func templateLanguages() []string {
+	var languages []string
+	for _, g := range generators.All() {
+		if hasTemplates(g) {
+			languages = append(languages, g.Language())
+		}
+	}
+	return languages
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cpm/generators"
+
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Templates(t *testing.T) {
+	dir := t.TempDir()
+	templates := filepath.Join(dir, "templates")
+
+	t.Run("should export the built-in templates", func(t *testing.T) {
+		require.NoError(t, newApp().Run([]string{"cpm", "templates", "export", "-o", templates}))
+
+		for _, language := range []string{"csharp", "java", "python"} {
+			assert.FileExists(t, filepath.Join(templates, generators.SDKOnChain, language, "contract.tmpl"))
+		}
+		for _, language := range []string{"java", "python"} {
+			assert.FileExists(t, filepath.Join(templates, generators.SDKOffChain, language, "contract.tmpl"))
+		}
+		for _, name := range []string{"api", "class", "index"} {
+			assert.FileExists(t, filepath.Join(templates, generators.SDKOffChain, "ts", name+".tmpl"))
+		}
+		assert.NoDirExists(t, filepath.Join(templates, generators.SDKOnChain, "go"))
+
+		err := newApp().Run([]string{"cpm", "templates", "export", "-o", templates, "python"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already exists")
+		require.NoError(t, newApp().Run([]string{"cpm", "templates", "export", "-o", templates, "--force", "python"}))
+
+		err = newApp().Run([]string{"cpm", "templates", "export", "-o", templates, "go"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "language 'go' has no templates")
+	})
+
+	m := manifest.NewManifest("Sample")
+	m.ABI.Methods = []manifest.Method{{Name: "symbol", ReturnType: smartcontract.StringType, Safe: true}}
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	manifestPath := filepath.Join(dir, "manifest.json")
+	require.NoError(t, os.WriteFile(manifestPath, data, 0644))
+
+	generate := func(t *testing.T, template string) string {
+		override := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(override, "contract.tmpl"), []byte(template), 0644))
+		out := t.TempDir()
+		require.NoError(t, newApp().Run([]string{"cpm", "generate", "python", "-m", manifestPath, "-t",
+			generators.SDKOffChain, "-o", out, "--templates", override}))
+		content, err := os.ReadFile(filepath.Join(out, "sample", "contract.py"))
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("should replace the built-in template", func(t *testing.T) {
+		content := generate(t, `# {{ UpperFirst .ContractName }} {{ range .Methods }}{{ MambaUnwrap .ReturnTypeABI }}{{ end }}`)
+		assert.Equal(t, "# Sample unwrap.as_str", content)
+	})
+
+	t.Run("should replace blocks of the built-in template", func(t *testing.T) {
+		content := generate(t, `{{ define "METHOD" }}
+	# {{ .NameABI }} is not supported{{ end }}`)
+		assert.Contains(t, content, "class Sample(GenericContract):")
+		assert.Contains(t, content, "# symbol is not supported")
+		assert.NotContains(t, content, "emit_contract_call")
+	})
+}
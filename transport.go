@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+)
+
+// hasTransportSettings returns true if connections to the hosts of the network need custom headers, a proxy or TLS
+// settings
+func (n *NetworkConfig) hasTransportSettings() bool {
+	return len(n.Headers) > 0 || n.Proxy != "" || n.CAFile != "" || n.ClientCert != "" || n.ClientKey != "" ||
+		n.InsecureSkipVerify
+}
+
+// httpTransport returns the transport to connect to the hosts of the network with
+func (n *NetworkConfig) httpTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if n.Proxy != "" {
+		proxy, err := url.Parse(n.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy '%s' for network '%s': %w", n.Proxy, n.Label, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+	if n.CAFile != "" {
+		pem, err := os.ReadFile(cfg.resolvePath(n.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file of network '%s': %w", n.Label, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s of network '%s'", n.CAFile, n.Label)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if n.ClientCert != "" || n.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.resolvePath(n.ClientCert), cfg.resolvePath(n.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate of network '%s': %w", n.Label, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// dialHost returns a neo-go RPC client for the host and the function to close it. The neo-go client does not allow to
+// configure its HTTP client, so its requests are sent by cpm with the headers, proxy and TLS settings of the network the
+// host belongs to. WebSocket hosts of networks without such settings are served by the neo-go WSClient. Requests in
+// flight are aborted when ctx is cancelled
+func dialHost(ctx context.Context, host string, timeout time.Duration) (*rpcclient.Client, func(), error) {
+	network := cfg.networkForHost(host)
+	if network == nil {
+		network = &NetworkConfig{}
+	}
+	if isWebSocketHost(host) && !network.hasTransportSettings() {
+		client, err := rpcclient.NewWS(ctx, host, rpcclient.WSOptions{
+			Options: rpcclient.Options{DialTimeout: timeout, RequestTimeout: timeout},
+		})
+		if err != nil {
+			return nil, nil, &hostError{fmt.Errorf("failed to connect to %s: %w", host, err)}
+		}
+		// the WSClient only uses ctx to connect
+		stop := context.AfterFunc(ctx, client.Close)
+		return &client.Client, func() {
+			stop()
+			client.Close()
+		}, nil
+	}
+
+	transport, err := network.httpTransport()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var send func(ctx context.Context, r *neorpc.Request) (*neorpc.Response, error)
+	closeConn := func() {}
+	if isWebSocketHost(host) {
+		conn, err := dialWebSocket(ctx, host, transport, network.Headers, timeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		send, closeConn = conn.send, conn.close
+	} else {
+		send = httpSender(host, &http.Client{Transport: transport, Timeout: timeout}, network.Headers)
+	}
+
+	client, err := rpcclient.NewInternal(ctx, func(ctx context.Context, events chan<- neorpc.Notification) func(*neorpc.Request) (*neorpc.Response, error) {
+		// the client waits for the events channel to be closed when it is closed itself
+		go func() {
+			<-ctx.Done()
+			closeConn()
+			close(events)
+		}()
+		return func(r *neorpc.Request) (*neorpc.Response, error) {
+			return send(ctx, r)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &client.Client, client.Close, nil
+}
+
+// httpSender returns a function that posts JSON-RPC requests to the host
+func httpSender(host string, client *http.Client, headers map[string]string) func(context.Context, *neorpc.Request) (*neorpc.Response, error) {
+	return func(ctx context.Context, r *neorpc.Request) (*neorpc.Response, error) {
+		body, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, host, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		// like the neo-go client, a JSON-RPC error in the body is more relevant than the HTTP status
+		raw := new(neorpc.Response)
+		if err := json.NewDecoder(resp.Body).Decode(raw); err != nil {
+			if resp.StatusCode != http.StatusOK {
+				return nil, &hostError{fmt.Errorf("HTTP %d/%s", resp.StatusCode, http.StatusText(resp.StatusCode))}
+			}
+			return nil, &hostError{fmt.Errorf("JSON decoding: %w", err)}
+		}
+		return raw, nil
+	}
+}
+
+// wsConn sends JSON-RPC requests over a WebSocket connection and matches the responses to them by request ID.
+// Notifications are ignored, cpm does not subscribe to any. It is only used for networks with headers, proxy or TLS
+// settings, the neo-go WSClient connects with a dialer that can't be configured
+type wsConn struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *neorpc.Response
+	// err is the reason the connection was closed, done is closed at the same time
+	err  error
+	done chan struct{}
+}
+
+func dialWebSocket(ctx context.Context, host string, transport *http.Transport, headers map[string]string, timeout time.Duration) (*wsConn, error) {
+	dialer := websocket.Dialer{
+		Proxy:            transport.Proxy,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: timeout,
+	}
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	conn, _, err := dialer.DialContext(ctx, host, header)
+	if err != nil {
+		return nil, &hostError{fmt.Errorf("failed to connect to %s: %w", host, err)}
+	}
+
+	c := &wsConn{
+		conn:    conn,
+		timeout: timeout,
+		pending: make(map[string]chan *neorpc.Response),
+		done:    make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read delivers responses to the waiting requests until the connection fails or is closed
+func (c *wsConn) read() {
+	for {
+		resp := new(neorpc.Response)
+		if err := c.conn.ReadJSON(resp); err != nil {
+			c.mu.Lock()
+			c.err = &hostError{fmt.Errorf("WebSocket connection closed: %w", err)}
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[string(resp.ID)]
+		delete(c.pending, string(resp.ID))
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+func (c *wsConn) send(ctx context.Context, r *neorpc.Request) (*neorpc.Response, error) {
+	id := strconv.FormatUint(r.ID, 10)
+	ch := make(chan *neorpc.Response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	err := c.conn.WriteJSON(r)
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, &hostError{fmt.Errorf("no response to %s within %s", r.Method, c.timeout)}
+	}
+}
+
+func (c *wsConn) close() {
+	_ = c.conn.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Transport(t *testing.T) {
+	log.SetLevel(log.WarnLevel)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	c := util.Uint160{}
+	networks := cfg.Networks
+	t.Cleanup(func() {
+		cfg.Networks = networks
+		clients.close()
+	})
+
+	t.Run("should add headers to requests", func(t *testing.T) {
+		mock := newTestMockRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != "secret" {
+				http.Error(w, "missing API key", http.StatusUnauthorized)
+				return
+			}
+			mock.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{srv.URL}, Headers: map[string]string{"X-Api-Key": "secret"}}}
+		m, err := fetchManifest(&c, srv.URL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+	})
+
+	t.Run("should add headers to WebSocket connections", func(t *testing.T) {
+		mock := newTestMockRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != "secret" {
+				http.Error(w, "missing API key", http.StatusUnauthorized)
+				return
+			}
+			mock.ServeWebSocket(w, r)
+		}))
+		defer srv.Close()
+		host := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{host}, Headers: map[string]string{"X-Api-Key": "secret"}}}
+		m, err := fetchManifest(&c, host, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+	})
+
+	t.Run("should connect through proxy", func(t *testing.T) {
+		mock := newTestMockRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}})
+		var proxied atomic.Bool
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied.Store(true)
+			mock.ServeHTTP(w, r)
+		}))
+		defer proxy.Close()
+
+		host := "http://rpc.example.invalid:10332"
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{host}, Proxy: proxy.URL}}
+		m, err := fetchManifest(&c, host, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+		assert.True(t, proxied.Load())
+	})
+
+	t.Run("should verify hosts with CA file", func(t *testing.T) {
+		srv := httptest.NewTLSServer(newTestMockRpcServer(t, []RpcResponse{{"getcontractstate", contractStateResult}}))
+		defer srv.Close()
+
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{srv.URL}}}
+		_, err := fetchManifest(&c, srv.URL, nil)
+		require.Error(t, err)
+		clients.close()
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+		require.NoError(t, err)
+
+		cfg.Networks = []NetworkConfig{{Label: "priv", Hosts: []string{srv.URL}, CAFile: caFile}}
+		m, err := fetchManifest(&c, srv.URL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "01-simple", m.Name)
+	})
+}
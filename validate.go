@@ -0,0 +1,564 @@
+package main
+
+import (
+	"cpm/generators"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// sdkTypeKeys are the config keys of the SDK types
+var sdkTypeKeys = map[string]string{
+	generators.SDKOnChain:  "on-chain",
+	generators.SDKOffChain: "off-chain",
+}
+
+// yamlErrorLine matches the line number in errors reported by the YAML parser, i.e. 'yaml: line 3: ...'
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// configProblem is an issue found in the config file. Line and column are 0 if the location is unknown
+type configProblem struct {
+	line    int
+	column  int
+	message string
+}
+
+func (p configProblem) format(path string) string {
+	switch {
+	case p.line == 0:
+		return fmt.Sprintf("%s: %s", path, p.message)
+	case p.column == 0:
+		return fmt.Sprintf("%s:%d: %s", path, p.line, p.message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", path, p.line, p.column, p.message)
+	}
+}
+
+// configValidator collects the problems of a config file
+type configValidator struct {
+	root     *yaml.Node
+	config   *CPMConfig
+	problems []configProblem
+	// plugins are the generator plugins of the config, which are validated without being registered
+	plugins map[string]generators.Generator
+}
+
+// generator returns the generator of the language, taking the plugins of the config into account
+func (v *configValidator) generator(language string) generators.Generator {
+	if g, ok := v.plugins[language]; ok {
+		return g
+	}
+	return generators.Get(language)
+}
+
+// languages returns the sorted languages that support the SDK type, taking the plugins of the config into account
+func (v *configValidator) languages(sdkType string) []string {
+	languages := generators.Languages(sdkType)
+	for language, g := range v.plugins {
+		if generators.Supports(g, sdkType) && !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+func (v *configValidator) addf(node *yaml.Node, format string, args ...any) {
+	p := configProblem{message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		p.line, p.column = node.Line, node.Column
+	}
+	v.problems = append(v.problems, p)
+}
+
+// addYAMLError adds an error of the YAML parser or decoder. They only report the line, the column is the one of the
+// value on that line if there is one
+func (v *configValidator) addYAMLError(msg string) {
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		p := configProblem{line: line, message: m[2]}
+		if node := valueAtLine(v.root, line); node != nil {
+			p.column = node.Column
+		}
+		v.problems = append(v.problems, p)
+		return
+	}
+	v.problems = append(v.problems, configProblem{message: strings.TrimPrefix(msg, "yaml: ")})
+}
+
+// validateConfig checks the config file for unknown fields, values of the wrong type, references to undefined networks,
+// unsupported languages, colliding SDK destinations and missing neo-express files. An error is returned if the file
+// can't be read
+func validateConfig(path string) ([]configProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &configValidator{root: new(yaml.Node), config: &CPMConfig{path: path}}
+	if err := yaml.Unmarshal(data, v.root); err != nil {
+		v.addYAMLError(err.Error())
+		return v.problems, nil
+	}
+	lookup, err := envLookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateNode(v.root, lookup); err != nil {
+		v.addYAMLError(err.Error())
+		return v.problems, nil
+	}
+
+	v.checkFields(v.root, reflect.TypeOf(v.config).Elem())
+	if err := v.root.Decode(v.config); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			for _, msg := range typeErr.Errors {
+				v.addYAMLError(msg)
+			}
+		} else {
+			v.addYAMLError(err.Error())
+		}
+	}
+
+	v.checkNetworks()
+	v.checkContracts()
+	v.checkGenerators()
+	v.checkLanguages()
+	v.checkDestinations()
+	v.checkTemplates()
+	v.checkTools()
+	return v.problems, nil
+}
+
+// valueAtLine returns the first scalar value, not key, at the line
+func valueAtLine(node *yaml.Node, line int) *yaml.Node {
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if child.Kind == yaml.ScalarNode && child.Line == line {
+			return child
+		}
+		if n := valueAtLine(child, line); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// checkFields reports mapping keys that don't correspond to a field of t
+func (v *configValidator) checkFields(node *yaml.Node, t reflect.Type) {
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			v.checkFields(child, t)
+		}
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// types that decode themselves are checked by the decoder
+	if t == durationType || reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		inline := inlineMap(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			field, ok := fields[key.Value]
+			if !ok && inline != nil {
+				v.checkFields(node.Content[i+1], inline.Elem())
+				continue
+			}
+			if !ok {
+				if suggestion := closestName(key.Value, fields); suggestion != "" {
+					v.addf(key, "unknown field '%s', did you mean '%s'?", key.Value, suggestion)
+				} else {
+					v.addf(key, "unknown field '%s'", key.Value)
+				}
+				continue
+			}
+			v.checkFields(node.Content[i+1], field)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, child := range node.Content {
+			v.checkFields(child, t.Elem())
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			v.checkFields(node.Content[i], t.Elem())
+		}
+	}
+}
+
+// yamlFields returns the types of the exported fields of a struct by YAML key
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || options == "inline" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// inlineMap returns the type of the map that takes the keys of a mapping that are not fields of the struct, or nil
+func inlineMap(t reflect.Type) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, options, _ := strings.Cut(f.Tag.Get("yaml"), ","); options == "inline" && f.Type.Kind() == reflect.Map {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// closestName returns the field name the unknown key was most likely meant to be, or an empty string if none is close
+func closestName(key string, fields map[string]reflect.Type) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(s))
+	}
+	best, bestDistance := "", 3
+	for name := range fields {
+		if normalize(name) == normalize(key) {
+			return name
+		}
+		if d := levenshtein(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// node returns the node at the path of mapping keys and sequence indexes, or nil if it doesn't exist
+func (v *configValidator) node(path ...any) *yaml.Node {
+	n := v.root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	for _, p := range path {
+		var next *yaml.Node
+		switch p := p.(type) {
+		case string:
+			if n.Kind != yaml.MappingNode {
+				return nil
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == p {
+					next = n.Content[i+1]
+				}
+			}
+		case int:
+			if n.Kind == yaml.SequenceNode && p < len(n.Content) {
+				next = n.Content[p]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// nodeOr returns the node at the path, or the closest existing parent so that problems still point near their cause
+func (v *configValidator) nodeOr(path ...any) *yaml.Node {
+	for i := len(path); i >= 0; i-- {
+		if n := v.node(path[:i]...); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func (v *configValidator) hasNetwork(label string) bool {
+	for _, n := range v.config.Networks {
+		if n.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *configValidator) checkNetworks() {
+	seen := make(map[string]bool)
+	for i, n := range v.config.Networks {
+		if n.Label == "" {
+			v.addf(v.nodeOr("networks", i), "network without label")
+		} else if seen[n.Label] {
+			v.addf(v.nodeOr("networks", i, "label"), "network '%s' is defined more than once", n.Label)
+		}
+		seen[n.Label] = true
+		if len(n.Hosts) == 0 {
+			v.addf(v.nodeOr("networks", i, "hosts"), "network '%s' has no hosts", n.Label)
+		}
+	}
+
+	if network := v.config.Defaults.ContractSourceNetwork; network != "" && !v.hasNetwork(network) {
+		v.addf(v.nodeOr("defaults", "contract-source-network"), "source network '%s' is not defined in networks", network)
+	}
+}
+
+func (v *configValidator) checkContracts() {
+	for i, c := range v.config.Contracts {
+		if c.ScriptHash.Equals(util.Uint160{}) && c.NNS == nil && c.Native == nil {
+			v.addf(v.nodeOr("contracts", i), "contract '%s' needs a script-hash, nns or native", c.Label)
+		}
+		switch {
+		case c.SourceNetwork != nil:
+			if !v.hasNetwork(*c.SourceNetwork) {
+				v.addf(v.nodeOr("contracts", i, "source-network"), "source network '%s' of contract '%s' is not defined in networks",
+					*c.SourceNetwork, c.Label)
+			}
+		case v.config.Defaults.ContractSourceNetwork == "":
+			v.addf(v.nodeOr("contracts", i), "contract '%s' has no source-network and there is no default contract-source-network",
+				c.Label)
+		}
+		if c.Bindings != nil {
+			if _, err := readBindings(v.config.resolvePath(*c.Bindings)); err != nil {
+				v.addf(v.nodeOr("contracts", i, "bindings"), "invalid binding config of contract '%s': %v", c.Label, err)
+			}
+		}
+	}
+}
+
+func (v *configValidator) checkLanguages() {
+	check := func(gc *GenerateConfig, sdkType string, path ...any) {
+		if gc == nil {
+			return
+		}
+		for i, l := range gc.Languages {
+			if !slices.Contains(v.languages(sdkType), l) {
+				v.addf(v.nodeOr(append(path, sdkTypeKeys[sdkType], "languages", i)...),
+					"language '%s' is not supported for %s SDKs. Valid values are %s", l, sdkTypeKeys[sdkType],
+					strings.Join(v.languages(sdkType), ", "))
+			}
+		}
+		for _, l := range gc.SdkDestinations.languages() {
+			if v.generator(l) == nil {
+				v.addf(v.nodeOr(append(path, sdkTypeKeys[sdkType], "destinations", l)...),
+					"destination of unknown language '%s'. Configure its generator plugin in tools.generators", l)
+			}
+		}
+	}
+	check(v.config.Defaults.OnChain, generators.SDKOnChain, "defaults")
+	check(v.config.Defaults.OffChain, generators.SDKOffChain, "defaults")
+	for i, c := range v.config.Contracts {
+		check(c.OnChain, generators.SDKOnChain, "contracts", i)
+		check(c.OffChain, generators.SDKOffChain, "contracts", i)
+	}
+}
+
+// checkTemplates reports template folders of languages that are not generated from templates, folders that don't exist
+// and files that don't override a built-in template
+func (v *configValidator) checkTemplates() {
+	check := func(gc *GenerateConfig, sdkType string, path ...any) {
+		if gc == nil {
+			return
+		}
+		for _, l := range gc.Templates.languages() {
+			node := v.nodeOr(append(path, sdkTypeKeys[sdkType], "templates", l)...)
+			g := v.generator(l)
+			if g == nil {
+				v.addf(node, "templates of unknown language '%s'", l)
+				continue
+			}
+			templates := g.Templates(sdkType)
+			if templates == nil {
+				v.addf(node, "%s %s SDKs are not generated from templates", sdkTypeKeys[sdkType], l)
+				continue
+			}
+			dir := v.config.resolvePath(*gc.Templates.get(l))
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				v.addf(node, "template folder %s does not exist", dir)
+				continue
+			}
+			for _, e := range entries {
+				name, ok := strings.CutSuffix(e.Name(), generators.TemplateExt)
+				if _, builtIn := templates[name]; !ok || !builtIn {
+					v.addf(node, "%s does not override a built-in template. Valid names are %s", filepath.Join(dir, e.Name()),
+						strings.Join(slices.Sorted(maps.Keys(templates)), generators.TemplateExt+", ")+generators.TemplateExt)
+				}
+			}
+		}
+	}
+	check(v.config.Defaults.OnChain, generators.SDKOnChain, "defaults")
+	check(v.config.Defaults.OffChain, generators.SDKOffChain, "defaults")
+	for i, c := range v.config.Contracts {
+		check(c.OnChain, generators.SDKOnChain, "contracts", i)
+		check(c.OffChain, generators.SDKOffChain, "contracts", i)
+	}
+}
+
+// checkDestinations reports languages whose SDKs would be written to the same directory. Destinations of contracts are
+// reported at the contract if they are set there, else at the defaults
+func (v *configValidator) checkDestinations() {
+	type use struct{ sdkType, language string }
+	destinations := make(map[string]use)
+	reported := make(map[*yaml.Node]bool)
+	check := func(contract *ContractConfig, gc *GenerateConfig, sdkType string, path ...any) {
+		if gc == nil {
+			return
+		}
+		for _, l := range gc.Languages {
+			if !slices.Contains(v.languages(sdkType), l) {
+				continue
+			}
+			dest, source := v.config.sdkDestination("", contract, l, sdkType)
+			dest = filepath.Clean(dest)
+			u := use{sdkType, l}
+			other, ok := destinations[dest]
+			if !ok {
+				destinations[dest] = u
+				continue
+			}
+			if other == u {
+				continue
+			}
+			if source != "contract" {
+				path = []any{"defaults"}
+			}
+			node := v.nodeOr(append(path, sdkTypeKeys[sdkType], "destinations", l)...)
+			if reported[node] {
+				continue
+			}
+			reported[node] = true
+			v.addf(node, "%s %s SDKs would be written to %s, which is also the destination of %s %s SDKs",
+				sdkTypeKeys[sdkType], l, dest, sdkTypeKeys[other.sdkType], other.language)
+		}
+	}
+	check(nil, v.config.Defaults.OnChain, generators.SDKOnChain, "defaults")
+	check(nil, v.config.Defaults.OffChain, generators.SDKOffChain, "defaults")
+	for i := range v.config.Contracts {
+		c := &v.config.Contracts[i]
+		check(c, c.OnChain, generators.SDKOnChain, "contracts", i)
+		check(c, c.OffChain, generators.SDKOffChain, "contracts", i)
+	}
+}
+
+// checkGenerators collects the configured generator plugins, so their languages are known to the other checks, and
+// reports plugins that can't be used or whose executable doesn't exist
+func (v *configValidator) checkGenerators() {
+	v.plugins = make(map[string]generators.Generator)
+	for _, language := range slices.Sorted(maps.Keys(v.config.Tools.Generators)) {
+		path := v.config.Tools.Generators[language]
+		executable := v.config.resolveExecutable(path)
+		node := v.nodeOr("tools", "generators", language)
+		plugin, err := generators.NewPlugin(language, executable)
+		if err != nil {
+			v.addf(node, "%v", err)
+			continue
+		}
+		v.plugins[language] = plugin
+		if _, err := exec.LookPath(executable); err != nil {
+			v.addf(node, "generator plugin executable %s for language '%s' is not found", executable, language)
+		}
+	}
+}
+
+func (v *configValidator) checkTools() {
+	destination := v.config.Defaults.ContractDestination
+	if destination != "" && destination != DESTINATION_NEO_EXPRESS && destination != DESTINATION_NEO_GO {
+		v.addf(v.nodeOr("defaults", "contract-destination"), "unknown contract destination '%s'. Valid values are %s and %s",
+			destination, DESTINATION_NEO_EXPRESS, DESTINATION_NEO_GO)
+	}
+
+	neoxp := v.config.Tools.NeoExpress
+	if neoxp.Downloader != "" && neoxp.Downloader != DOWNLOADER_NEOXP && neoxp.Downloader != DOWNLOADER_NATIVE {
+		v.addf(v.nodeOr("tools", "neo-express", "downloader"), "unknown neo-express downloader '%s'. Valid values are %s and %s",
+			neoxp.Downloader, DOWNLOADER_NEOXP, DOWNLOADER_NATIVE)
+	}
+	if destination == DESTINATION_NEO_GO {
+		configPath := v.config.Tools.NeoGo.ConfigPath
+		if configPath == "" {
+			v.addf(v.nodeOr("tools", "neo-go", "config-path"), "neo-go config-path is not set")
+		} else if _, err := os.Stat(v.config.resolvePath(configPath)); err != nil {
+			v.addf(v.nodeOr("tools", "neo-go", "config-path"), "neo-go node config %s does not exist",
+				v.config.resolvePath(configPath))
+		}
+		return
+	}
+	if destination != "" && destination != DESTINATION_NEO_EXPRESS {
+		return
+	}
+
+	if neoxp.ConfigPath == "" {
+		v.addf(v.nodeOr("tools", "neo-express", "config-path"), "neo-express config-path is not set")
+	} else if _, err := os.Stat(v.config.resolvePath(neoxp.ConfigPath)); err != nil {
+		v.addf(v.nodeOr("tools", "neo-express", "config-path"), "neo-express config %s does not exist",
+			v.config.resolvePath(neoxp.ConfigPath))
+	}
+
+	if neoxp.Downloader != "" && neoxp.Downloader != DOWNLOADER_NEOXP {
+		return
+	}
+	executable := "neoxp"
+	if neoxp.ExecutablePath != nil {
+		executable = v.config.resolveExecutable(*neoxp.ExecutablePath)
+	}
+	if filepath.Base(executable) != executable {
+		if _, err := os.Stat(executable); err != nil {
+			v.addf(v.nodeOr("tools", "neo-express", "executable-path"), "neoxp executable %s does not exist", executable)
+		}
+	} else if _, err := exec.LookPath(executable); err != nil {
+		v.addf(v.nodeOr("tools", "neo-express", "executable-path"), "neoxp executable '%s' is not found in $PATH", executable)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"cpm/generators"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DEFAULT_CONFIG_FILE)
+	require.NoError(t, os.WriteFile(path, []byte(strings.TrimPrefix(content, "\n")), 0644))
+	return path
+}
+
+func formatProblems(path string, problems []configProblem) []string {
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, strings.TrimPrefix(p.format(path), path))
+	}
+	return lines
+}
+
+func Test_ValidateConfig(t *testing.T) {
+	t.Run("should accept a valid config", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  on-chain:
+    languages:
+      - python
+  off-chain:
+    languages:
+      - python
+      - ts
+contracts:
+  - label: Props - puppet
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+  - label: GAS
+    native: GasToken
+    source-network: testnet
+tools:
+  neo-express:
+    config-path: default.neo-express
+    downloader: native
+networks:
+  - label: mainnet
+    timeout: 10s
+    hosts:
+      - http://127.0.0.1:10332
+  - label: testnet
+    hosts:
+      - http://127.0.0.1:20332
+`)
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "default.neo-express"), []byte("{}"), 0644))
+
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Empty(t, formatProblems(path, problems))
+	})
+
+	t.Run("should report problems with their location", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  contract-destination: neo-go
+  on-chain:
+    languages:
+      - python
+      - ts
+    destinations:
+      python: sdk
+  off-chain:
+    languages:
+      - python
+    destinations:
+      python: ./sdk/
+contracts:
+  - label: A
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+    sourcenetwork: testnet
+  - label: B
+    script-hash: '0x0e312c70ce6ed18d5702c6c5794c493d9ef46dc9'
+    source-network: testnet
+networks:
+  - label: mainnet
+    magic: abc
+    hosts:
+      - http://127.0.0.1:10332
+tools:
+  neo-go:
+    config-path: cpm.yaml
+`)
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":18:5: unknown field 'sourcenetwork', did you mean 'source-network'?",
+			":24:12: cannot unmarshal !!str `abc` into uint32",
+			":21:21: source network 'testnet' of contract 'B' is not defined in networks",
+			":7:9: language 'ts' is not supported for on-chain SDKs. Valid values are csharp, go, java, python",
+			":14:15: off-chain python SDKs would be written to " + filepath.Join(filepath.Dir(path), "sdk") +
+				", which is also the destination of on-chain python SDKs",
+		}, formatProblems(path, problems))
+	})
+
+	t.Run("should report colliding contract destinations", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  contract-destination: neo-go
+  off-chain:
+    languages:
+      - ts
+contracts:
+  - label: A
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+    on-chain:
+      languages:
+        - python
+      destinations:
+        python: cpm_out/offchain/ts
+networks:
+  - label: mainnet
+    hosts:
+      - http://127.0.0.1:10332
+tools:
+  neo-go:
+    config-path: cpm.yaml
+`)
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":14:17: on-chain python SDKs would be written to " + filepath.Join(filepath.Dir(path), "cpm_out", "offchain", "ts") +
+				", which is also the destination of off-chain ts SDKs",
+		}, formatProblems(path, problems))
+	})
+
+	t.Run("should report generator plugins", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  contract-destination: neo-go
+  off-chain:
+    languages:
+      - kotlin
+    destinations:
+      kotlin: sdk/kotlin
+      rust: sdk/rust
+tools:
+  generators:
+    go: cpm-gen-go
+    kotlin: tools/cpm-gen-kotlin
+  neo-go:
+    config-path: cpm.yaml
+networks:
+  - label: mainnet
+    hosts:
+      - http://127.0.0.1:10332
+`)
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":12:9: language 'go' is built in and can't be provided by generator plugin cpm-gen-go",
+			":13:13: generator plugin executable " + filepath.Join(filepath.Dir(path), "tools", "cpm-gen-kotlin") +
+				" for language 'kotlin' is not found",
+			":9:13: destination of unknown language 'rust'. Configure its generator plugin in tools.generators",
+		}, formatProblems(path, problems))
+		assert.Nil(t, generators.Get("kotlin"), "validation must not register plugins")
+	})
+
+	t.Run("should report template overrides", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  contract-destination: neo-go
+  off-chain:
+    languages:
+      - go
+      - ts
+    templates:
+      go: templates/go
+      ts: templates/ts
+contracts:
+  - label: A
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+    on-chain:
+      languages:
+        - python
+      templates:
+        python: templates/python
+networks:
+  - label: mainnet
+    hosts:
+      - http://127.0.0.1:10332
+tools:
+  neo-go:
+    config-path: cpm.yaml
+`)
+		dir := filepath.Dir(path)
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates", "ts"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "ts", "class.tmpl"), nil, 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "ts", "contract.tmpl"), nil, 0644))
+
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":9:11: off-chain go SDKs are not generated from templates",
+			":10:11: " + filepath.Join(dir, "templates", "ts", "contract.tmpl") +
+				" does not override a built-in template. Valid names are api.tmpl, class.tmpl, index.tmpl",
+			":18:17: template folder " + filepath.Join(dir, "templates", "python") + " does not exist",
+		}, formatProblems(path, problems))
+	})
+
+	t.Run("should report invalid binding configs", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+  contract-destination: neo-go
+contracts:
+  - label: A
+    script-hash: '0x76a8f8a7a901b29a33013b469949f4b08db15756'
+    bindings: a.yml
+  - label: B
+    script-hash: '0x0e312c70ce6ed18d5702c6c5794c493d9ef46dc9'
+    bindings: b.yml
+networks:
+  - label: mainnet
+    hosts:
+      - http://127.0.0.1:10332
+tools:
+  neo-go:
+    config-path: cpm.yaml
+`)
+		dir := filepath.Dir(path)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yml"), []byte("types:\n  symbol:\n    base: Text\n"), 0644))
+
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":7:15: invalid binding config of contract 'A': open " + filepath.Join(dir, "a.yml") + ": no such file or directory",
+			":10:15: invalid binding config of contract 'B': can't parse " + filepath.Join(dir, "b.yml") + ": bad parameter type: Text",
+		}, formatProblems(path, problems))
+	})
+
+	t.Run("should report missing neo-express files", func(t *testing.T) {
+		path := writeTestConfig(t, `
+defaults:
+  contract-source-network: mainnet
+tools:
+  neo-express:
+    executable-path: tools/neoxp
+    config-path: default.neo-express
+networks:
+  - label: mainnet
+    hosts:
+      - http://127.0.0.1:10332
+`)
+		dir := filepath.Dir(path)
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			":6:18: neo-express config " + filepath.Join(dir, "default.neo-express") + " does not exist",
+			":5:22: neoxp executable " + filepath.Join(dir, "tools", "neoxp") + " does not exist",
+		}, formatProblems(path, problems))
+	})
+
+	t.Run("should report syntax errors", func(t *testing.T) {
+		path := writeTestConfig(t, "defaults:\n  contract-source-network: main: net\n")
+		problems, err := validateConfig(path)
+		require.NoError(t, err)
+		require.Len(t, problems, 1)
+		assert.Equal(t, 2, problems[0].line)
+	})
+}
+
+// Test_ConfigSchema ensures the published JSON Schema describes exactly the fields of the config and the languages of
+// the generators
+func Test_ConfigSchema(t *testing.T) {
+	t.Run("should describe the config", func(t *testing.T) {
+		checkConfigSchema(t, configSchema)
+	})
+
+	t.Run("should describe the languages of generator plugins", func(t *testing.T) {
+		require.NoError(t, generators.RegisterPlugin("kotlin", "cpm-gen-kotlin"))
+		t.Cleanup(func() { generators.Unregister("kotlin") })
+
+		schema, err := generatorSchema()
+		require.NoError(t, err)
+		checkConfigSchema(t, schema)
+		assert.Contains(t, string(schema), `"kotlin"`)
+	})
+}
+
+func checkConfigSchema(t *testing.T, data []byte) {
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+	definitions := schema["definitions"].(map[string]any)
+	var languages []string
+	for _, g := range generators.All() {
+		languages = append(languages, g.Language())
+	}
+
+	var check func(path string, s map[string]any, typ reflect.Type)
+	check = func(path string, s map[string]any, typ reflect.Type) {
+		if ref, ok := s["$ref"].(string); ok {
+			s = definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any)
+		}
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Slice:
+			check(path+"[]", s["items"].(map[string]any), typ.Elem())
+		case reflect.Struct:
+			if reflect.PointerTo(typ).Implements(yamlUnmarshalerType) {
+				return
+			}
+			properties, ok := s["properties"].(map[string]any)
+			require.True(t, ok, "%s has no properties in the schema", path)
+			if inlineMap(typ) != nil {
+				assert.IsType(t, map[string]any{}, s["additionalProperties"], "%s does not allow additional properties", path)
+			} else {
+				assert.Equal(t, false, s["additionalProperties"], "%s allows additional properties", path)
+			}
+
+			fields := yamlFields(typ)
+			var want, got []string
+			for name := range fields {
+				want = append(want, name)
+			}
+			if typ == reflect.TypeOf(SdkDestination{}) {
+				want = languages
+			}
+			for name := range properties {
+				got = append(got, name)
+			}
+			sort.Strings(want)
+			sort.Strings(got)
+			require.Equal(t, want, got, "properties of %s", path)
+
+			for name, field := range fields {
+				check(path+"."+name, properties[name].(map[string]any), field)
+			}
+		}
+	}
+	check("cpm.yaml", schema, reflect.TypeOf(CPMConfig{}))
+}